@@ -0,0 +1,158 @@
+/*
+ * autosave.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	flamedata "github.com/isangeles/flame/data"
+	flameres "github.com/isangeles/flame/data/res"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/response"
+)
+
+// Infix of autosave snapshot names.
+const autosaveInfix = "-autosave-"
+
+// Layout of the time in autosave snapshot names.
+const autosaveTimeLayout = "20060102-150405"
+
+var snapshotMutex sync.Mutex
+
+// autosave exports the game module to a new snapshot if the
+// autosave interval from the config package passed since the
+// last save.
+// Should be called by the server update loop, so the module
+// state and the journal sequence number are read together,
+// between handling of client requests and between the game
+// updates.
+func (g *Game) autosave() {
+	if config.AutosaveInterval < 1 {
+		return
+	}
+	interval := time.Duration(config.AutosaveInterval) * time.Millisecond
+	if g.lastSave.IsZero() {
+		g.lastSave = time.Now()
+	}
+	if time.Since(g.lastSave) < interval {
+		return
+	}
+	g.lastSave = time.Now()
+	saveTime := time.Now()
+	data := g.Snapshot()
+	seq := journal.Seq()
+	go func() {
+		err := saveSnapshot(data, seq, saveTime)
+		if err != nil {
+			log.Printf("Game: unable to autosave: %v", err)
+		}
+	}()
+}
+
+// saveSnapshot exports specified module data to the new
+// autosave snapshot in the modules directory and removes
+// the oldest snapshots of the module above the snapshots limit
+// from the config package.
+// Specified sequence number should be the number of the last
+// journal entry included in the module data, and specified time
+// the time when the data was taken from the game.
+func saveSnapshot(data flameres.ModuleData, seq int64, saveTime time.Time) error {
+	snapshotMutex.Lock()
+	defer snapshotMutex.Unlock()
+	name := data.ID + autosaveInfix + time.Now().Format(autosaveTimeLayout)
+	err := flamedata.ExportModule(filepath.Join(config.ModulesPath, name), data)
+	if err != nil {
+		return fmt.Errorf("unable to export module: %v", err)
+	}
+	log.Printf("Game saved: %s", name)
+	err = journal.Compact(name, seq, saveTime)
+	if err != nil {
+		log.Printf("Game: unable to compact journal: %v", err)
+	}
+	snapshots, err := moduleSaves(data.ID + autosaveInfix)
+	if err != nil {
+		return fmt.Errorf("unable to list snapshots: %v", err)
+	}
+	if config.AutosaveSnapshots < 1 || len(snapshots) <= config.AutosaveSnapshots {
+		return nil
+	}
+	for _, s := range snapshots[:len(snapshots)-config.AutosaveSnapshots] {
+		path := filepath.Join(config.ModulesPath, s.Name+flamedata.ModuleFileExt)
+		err := os.Remove(path)
+		if err != nil {
+			log.Printf("Game: unable to remove snapshot: %s: %v", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// moduleSaves returns all module files from the modules directory
+// with names starting with specified prefix, sorted from the oldest
+// to the newest.
+func moduleSaves(prefix string) ([]response.Save, error) {
+	files, err := ioutil.ReadDir(config.ModulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %v", err)
+	}
+	saves := make([]response.Save, 0)
+	for _, info := range files {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), flamedata.ModuleFileExt) {
+			continue
+		}
+		if !strings.HasPrefix(info.Name(), prefix) {
+			continue
+		}
+		save := response.Save{
+			Name: strings.TrimSuffix(info.Name(), flamedata.ModuleFileExt),
+			Time: info.ModTime().UnixMilli(),
+		}
+		saves = append(saves, save)
+	}
+	sort.SliceStable(saves, func(i, j int) bool {
+		if saves[i].Time == saves[j].Time {
+			return saves[i].Name < saves[j].Name
+		}
+		return saves[i].Time < saves[j].Time
+	})
+	return saves, nil
+}
+
+// handleSavesRequest handles saves request.
+// Only admin users are allowed to list saves.
+func handleSavesRequest(cli *Client) ([]response.Save, error) {
+	if !cli.User().Admin {
+		return nil, fmt.Errorf("You are not an admin")
+	}
+	saves, err := moduleSaves("")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list saves: %v", err)
+	}
+	return saves, nil
+}
@@ -0,0 +1,100 @@
+/*
+ * chars.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+	"github.com/isangeles/fire/user"
+)
+
+// handleListCharsRequest handles list chars request.
+func handleListCharsRequest(cli *Client) (resp []response.CharList) {
+	game.UpdateUserChars(cli.User())
+	for _, c := range game.userOwnedChars(cli.User()) {
+		data := c.Data()
+		charResp := response.CharList{
+			ID:     c.ID(),
+			Serial: c.Serial(),
+			Level:  data.Level,
+			Race:   data.Race,
+			Sex:    data.Sex,
+			Active: cli.User().Active(c.ID(), c.Serial()),
+		}
+		area := game.Chapter().ObjectArea(c)
+		if area != nil {
+			charResp.Area = area.ID()
+		}
+		resp = append(resp, charResp)
+	}
+	for _, c := range cli.User().OfflineChars() {
+		charResp := response.CharList{
+			ID:      c.Char.ID,
+			Serial:  c.Char.Serial,
+			Level:   c.Char.Level,
+			Race:    c.Char.Race,
+			Sex:     c.Char.Sex,
+			Area:    c.Area,
+			Offline: true,
+		}
+		resp = append(resp, charResp)
+	}
+	return
+}
+
+// handleSelectCharsRequest handles select chars request.
+func handleSelectCharsRequest(cli *Client, req []request.Character) error {
+	var chars []user.Character
+	for _, c := range req {
+		chars = append(chars, user.Character{c.ID, c.Serial})
+	}
+	return game.SelectUserChars(cli.User(), chars...)
+}
+
+// handleDeleteCharRequest handles delete char request.
+func handleDeleteCharRequest(cli *Client, req request.Character) error {
+	return game.DeleteUserChar(cli.User(), req.ID, req.Serial)
+}
+
+// charsLimit returns the maximal number of characters
+// for specified user, or 0 if there is no limit.
+func charsLimit(usr *user.User) int {
+	if usr.MaxChars > 0 {
+		return usr.MaxChars
+	}
+	return config.UserMaxChars
+}
+
+// charsLimitReached checks if specified user reached
+// the maximal number of characters.
+func charsLimitReached(usr *user.User) error {
+	limit := charsLimit(usr)
+	if limit < 1 {
+		return nil
+	}
+	if len(usr.Chars())+len(usr.OfflineChars()) >= limit {
+		return fmt.Errorf("Characters limit reached: %d", limit)
+	}
+	return nil
+}
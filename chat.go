@@ -0,0 +1,330 @@
+/*
+ * chat.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isangeles/flame/area"
+	"github.com/isangeles/flame/objects"
+	"github.com/isangeles/flame/serial"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+	"github.com/isangeles/fire/user"
+)
+
+var chatFilter []*regexp.Regexp
+
+// handleChatRequest handles chat request.
+func handleChatRequest(cli *Client, req request.Chat) error {
+	err := moderateChat(cli, &req)
+	if err != nil {
+		return err
+	}
+	if req.Channel == request.ChatAdmin {
+		return handleAdminChat(cli, req)
+	}
+	// Retrieve object.
+	ob := serial.Object(req.ObjectID, req.ObjectSerial)
+	if ob == nil {
+		return fmt.Errorf("Object not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object is not controled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	logger, ok := ob.(objects.Logger)
+	if !ok {
+		return fmt.Errorf("Object is has no chat log: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	// Find message recipients.
+	if len(req.Channel) < 1 {
+		req.Channel = request.ChatSay
+	}
+	var notify func(resp response.Response)
+	switch req.Channel {
+	case request.ChatSay:
+		areaOb, ok := logger.(area.Object)
+		if !ok {
+			break
+		}
+		notify = func(resp response.Response) { game.NotifyNearObjects(areaOb, resp) }
+	case request.ChatArea:
+		areaOb, ok := logger.(area.Object)
+		if !ok {
+			return fmt.Errorf("Object is not in any area: %s %s", req.ObjectID,
+				req.ObjectSerial)
+		}
+		area := game.Chapter().ObjectArea(areaOb)
+		if area == nil {
+			return fmt.Errorf("Object is not in any area: %s %s", req.ObjectID,
+				req.ObjectSerial)
+		}
+		notify = func(resp response.Response) { go game.NotifyAreaObjects(area, resp) }
+	case request.ChatWhisper:
+		whisper, err := whisperNotify(cli.User(), req.To)
+		if err != nil {
+			return err
+		}
+		notify = whisper
+	case request.ChatParty:
+		p := userParty(cli.User().ID())
+		if p == nil {
+			return fmt.Errorf("Not in party")
+		}
+		notify = p.Notify
+	case request.ChatGuild:
+		g := userGuild(cli.User().ID())
+		if g == nil {
+			return fmt.Errorf("Not in guild")
+		}
+		notify = g.Notify
+	case request.ChatGlobal:
+		notify = func(resp response.Response) {
+			sendResp := func() { userResponses <- userResponse{Response: resp} }
+			go sendResp()
+		}
+	default:
+		return fmt.Errorf("Unknown chat channel: %s", req.Channel)
+	}
+	msg := objects.NewMessage(req.Message, req.Translated)
+	logger.ChatLog().Add(msg)
+	recordChat(cli, req)
+	game.fireEvent(chatEvent, cli.User().ID(), scriptTarget(req.ObjectID, req.ObjectSerial),
+		req.Channel, scriptText(req.Message))
+	if notify == nil {
+		return nil
+	}
+	// Notify recipients.
+	chatResp := response.Chat{
+		ObjectID:     req.ObjectID,
+		ObjectSerial: req.ObjectSerial,
+		Message:      req.Message,
+		Translated:   req.Translated,
+		Time:         msg.Time,
+		Channel:      req.Channel,
+	}
+	resp := response.Response{Chat: []response.Chat{chatResp}}
+	notify(resp)
+	return nil
+}
+
+// handleAdminChat handles chat request on the admin channel.
+// Only admin users are allowed to send messages on this channel,
+// messages are sent to all logged clients.
+func handleAdminChat(cli *Client, req request.Chat) error {
+	if !cli.User().Admin {
+		return fmt.Errorf("Admin channel is not available for user: %s",
+			cli.User().ID())
+	}
+	broadcast(req.Message, req.Translated)
+	recordChat(cli, req)
+	return nil
+}
+
+// handleChatHistoryRequest handles chat-history request.
+// Only admin users are allowed to review the chat history.
+func handleChatHistoryRequest(cli *Client, req request.ChatHistory) ([]response.ChatRecord, error) {
+	if !cli.User().Admin {
+		return nil, fmt.Errorf("Chat history is not available for user: %s",
+			cli.User().ID())
+	}
+	var records []response.ChatRecord
+	for _, r := range chatHistory.Records(req) {
+		record := response.ChatRecord{
+			UserID:       r.UserID,
+			ObjectID:     r.ObjectID,
+			ObjectSerial: r.ObjectSerial,
+			Channel:      r.Channel,
+			To:           r.To,
+			Message:      r.Message,
+			Time:         r.Time,
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// handleMuteRequest handles mute request.
+// Only admin users are allowed to mute other users,
+// mute time set to 0 unmutes the user.
+func handleMuteRequest(cli *Client, req request.Mute) error {
+	if !cli.User().Admin {
+		return fmt.Errorf("Mute is not available for user: %s",
+			cli.User().ID())
+	}
+	usr := data.User(req.UserID)
+	if usr == nil {
+		return fmt.Errorf("User not found: %s", req.UserID)
+	}
+	usr.MutedUntil = 0
+	if req.Time > 0 {
+		usr.MutedUntil = time.Now().UnixMilli() + req.Time
+	}
+	saveUser(usr)
+	return nil
+}
+
+// moderateChat checks if the client is allowed to send message from
+// specified chat request, and filters the message.
+func moderateChat(cli *Client, req *request.Chat) error {
+	if cli.User().Muted() {
+		return fmt.Errorf("User is muted: %s", cli.User().ID())
+	}
+	if !cli.AllowChat() {
+		return fmt.Errorf("Chat rate limit exceeded")
+	}
+	if config.ChatMaxLen > 0 && len([]rune(req.Message)) > config.ChatMaxLen {
+		return fmt.Errorf("Message too long: %d > %d", len([]rune(req.Message)),
+			config.ChatMaxLen)
+	}
+	for _, f := range chatFilter {
+		req.Message = filterWords(req.Message, f)
+	}
+	return nil
+}
+
+// filterWords masks all words from specified message matching
+// specified filter.
+// Matches inside other words are not masked.
+func filterWords(msg string, filter *regexp.Regexp) string {
+	filtered := strings.Builder{}
+	last := 0
+	for _, m := range filter.FindAllStringIndex(msg, -1) {
+		if !wordBoundaries(msg, m[0], m[1]) {
+			continue
+		}
+		filtered.WriteString(msg[last:m[0]])
+		filtered.WriteString(strings.Repeat("*", utf8.RuneCountInString(msg[m[0]:m[1]])))
+		last = m[1]
+	}
+	filtered.WriteString(msg[last:])
+	return filtered.String()
+}
+
+// wordBoundaries checks if specified start and end positions
+// of specified text are word boundaries.
+func wordBoundaries(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	if start > 0 && isWordRune(before) {
+		return false
+	}
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	if end < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+// isWordRune checks if specified rune is a part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// recordChat adds message from specified chat request to the
+// chat history.
+func recordChat(cli *Client, req request.Chat) {
+	record := res.ChatRecordData{
+		UserID:       cli.User().ID(),
+		ObjectID:     req.ObjectID,
+		ObjectSerial: req.ObjectSerial,
+		Channel:      req.Channel,
+		To:           req.To,
+		Message:      req.Message,
+		Time:         time.Now().UnixMilli(),
+	}
+	chatHistory.Add(record)
+}
+
+// loadChatFilter loads filtered chat words from the chat
+// filter file in the server directory.
+func loadChatFilter() error {
+	words, err := data.ImportChatFilter(config.ChatFilterFile)
+	if err != nil {
+		return err
+	}
+	chatFilter = nil
+	for _, w := range words {
+		if len(w) < 1 {
+			continue
+		}
+		filter := regexp.MustCompile("(?i)" + regexp.QuoteMeta(w))
+		chatFilter = append(chatFilter, filter)
+	}
+	return nil
+}
+
+// whisperNotify returns function for sending response from specified
+// user to the whisper recipient with specified ID.
+// The recipient can be a user or a game character.
+// Responses to recipients that ignore the user are dropped.
+func whisperNotify(usr *user.User, to string) (func(resp response.Response), error) {
+	if recipient := data.User(to); recipient != nil {
+		if recipient.Ignores(usr.ID()) {
+			return func(resp response.Response) {}, nil
+		}
+		notify := func(resp response.Response) {
+			sendResp := func() { userResponses <- userResponse{Response: resp, UserID: to} }
+			go sendResp()
+		}
+		return notify, nil
+	}
+	for _, c := range game.Chapter().Characters() {
+		if c.ID() != to {
+			continue
+		}
+		if ignoredBy(usr, c.ID(), c.Serial()) {
+			return func(resp response.Response) {}, nil
+		}
+		notify := func(resp response.Response) {
+			charResp := charResponse{Response: resp, CharID: c.ID(), CharSerial: c.Serial()}
+			sendResp := func() { charResponses <- charResp }
+			go sendResp()
+		}
+		return notify, nil
+	}
+	return nil, fmt.Errorf("Whisper recipient not found: %s", to)
+}
+
+// broadcast sends specified message on the admin channel
+// to all logged clients.
+func broadcast(msg string, translated bool) {
+	chatResp := response.Chat{
+		Message:    msg,
+		Translated: translated,
+		Time:       time.Now(),
+		Channel:    request.ChatAdmin,
+	}
+	resp := response.Response{Chat: []response.Chat{chatResp}}
+	sendResp := func() { userResponses <- userResponse{Response: resp} }
+	go sendResp()
+}
@@ -0,0 +1,106 @@
+/*
+ * chathistory.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"log"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/request"
+)
+
+var chatHistory = newChatLog("")
+
+// Struct for chat history.
+// Chat log keeps the newest chat records in memory,
+// and appends all records to the history file.
+// The history file is truncated to the chat history size
+// after the number of appended records exceeds twice that size.
+type chatLog struct {
+	path        string
+	records     []res.ChatRecordData
+	fileRecords int
+}
+
+// newChatLog creates new chat log for history file with specified path.
+// Empty path means that the records are not saved.
+func newChatLog(path string, records ...res.ChatRecordData) *chatLog {
+	l := chatLog{path: path, records: records, fileRecords: len(records)}
+	return &l
+}
+
+// Add adds specified record to the chat log.
+func (l *chatLog) Add(record res.ChatRecordData) {
+	l.records = append(l.records, record)
+	if config.ChatHistorySize > 0 && len(l.records) > config.ChatHistorySize {
+		l.records = l.records[len(l.records)-config.ChatHistorySize:]
+	}
+	if len(l.path) < 1 {
+		return
+	}
+	err := data.AppendChatHistory(l.path, record)
+	if err != nil {
+		log.Printf("Chat log: unable to save record: %v", err)
+		return
+	}
+	l.fileRecords++
+	if config.ChatHistorySize > 0 && l.fileRecords > config.ChatHistorySize*2 {
+		err := l.Compact()
+		if err != nil {
+			log.Printf("Chat log: unable to compact history: %v", err)
+		}
+	}
+}
+
+// Compact replaces all records in the history file with
+// the records kept in memory.
+func (l *chatLog) Compact() error {
+	if len(l.path) < 1 {
+		return nil
+	}
+	err := data.SaveChatHistory(l.path, l.records)
+	if err != nil {
+		return err
+	}
+	l.fileRecords = len(l.records)
+	return nil
+}
+
+// Records returns the newest records matching specified
+// chat history request.
+func (l *chatLog) Records(req request.ChatHistory) (records []res.ChatRecordData) {
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if req.Limit > 0 && len(records) >= req.Limit {
+			break
+		}
+		r := l.records[i]
+		if len(req.Channel) > 0 && r.Channel != req.Channel {
+			continue
+		}
+		if len(req.UserID) > 0 && r.UserID != req.UserID && r.To != req.UserID {
+			continue
+		}
+		records = append([]res.ChatRecordData{r}, records...)
+	}
+	return
+}
@@ -21,8 +21,11 @@
 package main
 
 import (
+	"time"
+
 	"github.com/gorilla/websocket"
 
+	"github.com/isangeles/fire/config"
 	"github.com/isangeles/fire/response"
 	"github.com/isangeles/fire/user"
 )
@@ -30,8 +33,12 @@ import (
 // Struct for the server client.
 type Client struct {
 	*websocket.Conn
-	user *user.User
-	Out  chan response.Response
+	user      *user.User
+	moveStart time.Time
+	moveCount int
+	chatStart time.Time
+	chatCount int
+	Out       chan response.Response
 }
 
 // newClient makes new client from
@@ -62,3 +69,31 @@ func (c *Client) Close() {
 	}
 	c.Conn.Close()
 }
+
+// AllowMove checks if client didn't exceed the limit of move
+// requests per second specified in the config package.
+func (c *Client) AllowMove() bool {
+	if config.MoveRateLimit < 1 {
+		return true
+	}
+	if time.Since(c.moveStart) >= time.Second {
+		c.moveStart = time.Now()
+		c.moveCount = 0
+	}
+	c.moveCount++
+	return c.moveCount <= config.MoveRateLimit
+}
+
+// AllowChat checks if client didn't exceed the limit of chat
+// requests per minute specified in the config package.
+func (c *Client) AllowChat() bool {
+	if config.ChatRateLimit < 1 {
+		return true
+	}
+	if time.Since(c.chatStart) >= time.Minute {
+		c.chatStart = time.Now()
+		c.chatCount = 0
+	}
+	c.chatCount++
+	return c.chatCount <= config.ChatRateLimit
+}
@@ -25,9 +25,13 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/isangeles/flame/data/text"
 )
@@ -38,18 +42,102 @@ const (
 	ModulesPath      = "data/modules"
 	UsersPath        = "data/users"
 	ModuleServerPath = "fire" // path to the server directory inside module directory
+	MarketFile       = "data/market.json"
+	ChatFilterFile   = ".chatfilter"
+	ChatHistoryFile  = "data/chat-history"
+	GuildsPath       = "data/guilds"
+	JournalFile      = "data/journal"
+	// Logout policies.
+	LogoutFlag    = "flag"    // offline characters are marked with inactive flag
+	LogoutDespawn = "despawn" // offline characters are removed from the game world
 )
 
 var (
-	Host            = ""
-	Port            = "8000"
-	Module          = ""
-	UpdateBreak     = 1
-	ActionMinRange  = 50.0
-	Message         = ""
-	LootDespawnTime = int64(5000)
+	Host               = ""
+	Port               = "8000"
+	Module             = ""
+	UpdateBreak        = 1
+	ActionMinRange     = 50.0
+	Message            = ""
+	LootDespawnTime    = int64(5000)
+	LogoutPolicy       = LogoutFlag
+	UserMaxChars       = 0
+	MoveRateLimit      = 10
+	MoveSpeedTolerance = 1.5
+	PathCellSize       = 32.0
+	TradeTimeout       = int64(60000)
+	MarketCurrency     = ""
+	MarketFee          = 0
+	MarketListTime     = int64(86400000)
+	ChatRateLimit      = 20
+	ChatMaxLen         = 256
+	ChatHistorySize    = 1000
+	PartyMaxSize       = 5
+	MailMaxLen         = 2048
+	MailboxSize        = 100
+	ScriptMaxRuntime   = int64(0)
+	ScriptCommandLimit = 100
+	ScriptCommands     = []string{"engineshow", "resshow", "moduleshow", "chaptershow",
+		"areashow", "areaset", "objectshow", "objecthave", "objectset", "objectadd",
+		"objectremove", "objectuse"}
+	AutosaveInterval  = int64(0)
+	AutosaveSnapshots = 5
+	CharsSaveInterval = int64(300000)
+	ConfigWatch       = false
 )
 
+// Numeric configuration values, by keys from the configuration file.
+var (
+	intValues = map[string]*int{
+		"update-break":         &UpdateBreak,
+		"user-max-chars":       &UserMaxChars,
+		"move-rate-limit":      &MoveRateLimit,
+		"market-fee":           &MarketFee,
+		"chat-rate-limit":      &ChatRateLimit,
+		"chat-max-len":         &ChatMaxLen,
+		"chat-history-size":    &ChatHistorySize,
+		"party-max-size":       &PartyMaxSize,
+		"mail-max-len":         &MailMaxLen,
+		"mailbox-size":         &MailboxSize,
+		"script-command-limit": &ScriptCommandLimit,
+		"autosave-snapshots":   &AutosaveSnapshots,
+	}
+	int64Values = map[string]*int64{
+		"loot-despawn-time":   &LootDespawnTime,
+		"trade-timeout":       &TradeTimeout,
+		"market-list-time":    &MarketListTime,
+		"script-max-runtime":  &ScriptMaxRuntime,
+		"autosave-interval":   &AutosaveInterval,
+		"chars-save-interval": &CharsSaveInterval,
+	}
+	floatValues = map[string]*float64{
+		"action-min-range":     &ActionMinRange,
+		"move-speed-tolerance": &MoveSpeedTolerance,
+		"path-cell-size":       &PathCellSize,
+	}
+)
+
+var mutex sync.RWMutex
+
+// Struct for change of configuration value.
+type Change struct {
+	Key      string
+	Old, New []string
+}
+
+// RLock locks configuration values for reading.
+// Goroutines other than the server update loop should read
+// configuration values only under this lock, as the values
+// can be changed by the config reload.
+func RLock() {
+	mutex.RLock()
+}
+
+// RUnlock unlocks configuration values locked for reading.
+func RUnlock() {
+	mutex.RUnlock()
+}
+
 // Load load server configuration file.
 func Load() error {
 	// Open config file.
@@ -63,6 +151,53 @@ func Load() error {
 	if err != nil {
 		return fmt.Errorf("unable to unmarshal config: %v", err)
 	}
+	apply(conf)
+	return nil
+}
+
+// Reload loads server configuration file and returns changes of all
+// configuration values.
+// Values are changed only if all values from the file are valid.
+// Host, port, and module values are not changed, as these values
+// require the server restart.
+// Values are changed under the lock used by RLock.
+func Reload() ([]Change, error) {
+	// Open config file.
+	file, err := os.Open(ConfigFileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open config file: %v", err)
+	}
+	defer file.Close()
+	// Unmarshal config.
+	conf, err := text.UnmarshalConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal config: %v", err)
+	}
+	err = validate(conf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	delete(conf, "host")
+	delete(conf, "port")
+	delete(conf, "module")
+	mutex.Lock()
+	prevValues := values()
+	apply(conf)
+	newValues := values()
+	mutex.Unlock()
+	var changes []Change
+	for k, v := range newValues {
+		if strings.Join(v, ";") != strings.Join(prevValues[k], ";") {
+			changes = append(changes, Change{k, prevValues[k], v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// apply sets configuration values from specified config map.
+// Invalid numeric values are ignored.
+func apply(conf map[string][]string) {
 	if len(conf["host"]) > 0 {
 		Host = conf["host"][0]
 	}
@@ -72,28 +207,48 @@ func Load() error {
 	if len(conf["module"]) > 0 {
 		Module = conf["module"][0]
 	}
-	if len(conf["update-break"]) > 0 {
-		updateBreak, err := strconv.Atoi(conf["update-break"][0])
+	for k, p := range intValues {
+		if len(conf[k]) < 1 {
+			continue
+		}
+		v, err := intValue(conf, k)
+		if err == nil {
+			*p = int(v)
+		}
+	}
+	for k, p := range int64Values {
+		if len(conf[k]) < 1 {
+			continue
+		}
+		v, err := intValue(conf, k)
 		if err == nil {
-			UpdateBreak = updateBreak
+			*p = v
 		}
 	}
-	if len(conf["action-min-range"]) > 0 {
-		minRange, err := strconv.ParseFloat(conf["action-min-range"][0], 64)
+	for k, p := range floatValues {
+		if len(conf[k]) < 1 {
+			continue
+		}
+		v, err := floatValue(conf, k)
 		if err == nil {
-			ActionMinRange = minRange
+			*p = v
 		}
 	}
 	if len(conf["message"]) > 0 {
 		Message = conf["message"][0]
 	}
-	if len(conf["loot-despawn-time"]) > 0 {
-		despawnTime, err := strconv.Atoi(conf["loot-despawn-time"][0])
-		if err == nil {
-			LootDespawnTime = int64(despawnTime)
-		}
+	if len(conf["logout-policy"]) > 0 {
+		LogoutPolicy = conf["logout-policy"][0]
+	}
+	if len(conf["market-currency"]) > 0 {
+		MarketCurrency = conf["market-currency"][0]
+	}
+	if conf["script-commands"] != nil {
+		ScriptCommands = conf["script-commands"]
+	}
+	if len(conf["config-watch"]) > 0 {
+		ConfigWatch = conf["config-watch"][0] == "true"
 	}
-	return nil
 }
 
 // Save saves server configuration file.
@@ -104,6 +259,93 @@ func Save() error {
 		return fmt.Errorf("unable to create file: %v", err)
 	}
 	defer file.Close()
+	text := text.MarshalConfig(values())
+	// Write config to file.
+	write := bufio.NewWriter(file)
+	write.WriteString(text)
+	write.Flush()
+	return nil
+}
+
+// validate checks if all values in specified config map
+// are valid.
+func validate(conf map[string][]string) error {
+	for k := range intValues {
+		if len(conf[k]) < 1 {
+			continue
+		}
+		_, err := intValue(conf, k)
+		if err != nil {
+			return err
+		}
+	}
+	for k := range int64Values {
+		if len(conf[k]) < 1 {
+			continue
+		}
+		_, err := intValue(conf, k)
+		if err != nil {
+			return err
+		}
+	}
+	for k := range floatValues {
+		if len(conf[k]) < 1 {
+			continue
+		}
+		_, err := floatValue(conf, k)
+		if err != nil {
+			return err
+		}
+	}
+	if len(conf["logout-policy"]) > 0 {
+		policy := conf["logout-policy"][0]
+		if policy != LogoutFlag && policy != LogoutDespawn {
+			return fmt.Errorf("logout-policy: unknown policy: %s", policy)
+		}
+	}
+	if len(conf["config-watch"]) > 0 {
+		watch := conf["config-watch"][0]
+		if watch != "true" && watch != "false" {
+			return fmt.Errorf("config-watch: invalid boolean value: %s", watch)
+		}
+	}
+	return nil
+}
+
+// intValue parses and checks integer value with specified key
+// from specified config map.
+func intValue(conf map[string][]string, key string) (int64, error) {
+	v, err := strconv.ParseInt(conf[key][0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid integer value: %s", key, conf[key][0])
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("%s: negative value: %d", key, v)
+	}
+	if key == "market-fee" && v > 100 {
+		return 0, fmt.Errorf("%s: value greater than 100: %d", key, v)
+	}
+	return v, nil
+}
+
+// floatValue parses and checks float value with specified key
+// from specified config map.
+func floatValue(conf map[string][]string, key string) (float64, error) {
+	v, err := strconv.ParseFloat(conf[key][0], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s: invalid float value: %s", key, conf[key][0])
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("%s: negative value: %f", key, v)
+	}
+	if key == "path-cell-size" && v == 0 {
+		return 0, fmt.Errorf("%s: value must be greater than 0", key)
+	}
+	return v, nil
+}
+
+// values returns config map with all configuration values.
+func values() map[string][]string {
 	conf := make(map[string][]string)
 	conf["host"] = []string{Host}
 	conf["port"] = []string{Port}
@@ -112,12 +354,29 @@ func Save() error {
 	conf["action-min-range"] = []string{fmt.Sprintf("%f", ActionMinRange)}
 	conf["message"] = []string{Message}
 	conf["loot-despawn-time"] = []string{fmt.Sprintf("%d", LootDespawnTime)}
-	text := text.MarshalConfig(conf)
-	// Write config to file.
-	write := bufio.NewWriter(file)
-	write.WriteString(text)
-	write.Flush()
-	return nil
+	conf["logout-policy"] = []string{LogoutPolicy}
+	conf["user-max-chars"] = []string{fmt.Sprintf("%d", UserMaxChars)}
+	conf["move-rate-limit"] = []string{fmt.Sprintf("%d", MoveRateLimit)}
+	conf["move-speed-tolerance"] = []string{fmt.Sprintf("%f", MoveSpeedTolerance)}
+	conf["path-cell-size"] = []string{fmt.Sprintf("%f", PathCellSize)}
+	conf["trade-timeout"] = []string{fmt.Sprintf("%d", TradeTimeout)}
+	conf["market-currency"] = []string{MarketCurrency}
+	conf["market-fee"] = []string{fmt.Sprintf("%d", MarketFee)}
+	conf["market-list-time"] = []string{fmt.Sprintf("%d", MarketListTime)}
+	conf["chat-rate-limit"] = []string{fmt.Sprintf("%d", ChatRateLimit)}
+	conf["chat-max-len"] = []string{fmt.Sprintf("%d", ChatMaxLen)}
+	conf["chat-history-size"] = []string{fmt.Sprintf("%d", ChatHistorySize)}
+	conf["party-max-size"] = []string{fmt.Sprintf("%d", PartyMaxSize)}
+	conf["mail-max-len"] = []string{fmt.Sprintf("%d", MailMaxLen)}
+	conf["mailbox-size"] = []string{fmt.Sprintf("%d", MailboxSize)}
+	conf["script-max-runtime"] = []string{fmt.Sprintf("%d", ScriptMaxRuntime)}
+	conf["script-command-limit"] = []string{fmt.Sprintf("%d", ScriptCommandLimit)}
+	conf["script-commands"] = ScriptCommands
+	conf["autosave-interval"] = []string{fmt.Sprintf("%d", AutosaveInterval)}
+	conf["autosave-snapshots"] = []string{fmt.Sprintf("%d", AutosaveSnapshots)}
+	conf["chars-save-interval"] = []string{fmt.Sprintf("%d", CharsSaveInterval)}
+	conf["config-watch"] = []string{fmt.Sprintf("%v", ConfigWatch)}
+	return conf
 }
 
 // ModulePath returns path to the current module directory.
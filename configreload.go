@@ -0,0 +1,115 @@
+/*
+ * configreload.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isangeles/burn"
+
+	"github.com/isangeles/fire/config"
+)
+
+const configTool = "config"
+
+var configReload = make(chan string)
+
+// reloadConfig reloads the server configuration file and returns
+// keys of all changed values.
+// All changes are logged with specified source of the reload.
+// If the server message was changed, the new message is sent to
+// all logged clients.
+func reloadConfig(source string) ([]string, error) {
+	changes, err := config.Reload()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for _, c := range changes {
+		log.Printf("Config: %s: %s: '%s' -> '%s'", source, c.Key,
+			strings.Join(c.Old, ";"), strings.Join(c.New, ";"))
+		keys = append(keys, c.Key)
+		if c.Key == "message" {
+			pushMessage := func() { userResponses <- userResponse{} }
+			go pushMessage()
+		}
+	}
+	log.Printf("Config reloaded: %s: changed values: %d", source, len(changes))
+	return keys, nil
+}
+
+// watchConfig checks the modification time of the server
+// configuration file every second and requests config reload
+// after each file modification, if config watch is enabled
+// in the config package.
+func watchConfig() {
+	var modTime time.Time
+	if info, err := os.Stat(config.ConfigFileName); err == nil {
+		modTime = info.ModTime()
+	}
+	for range time.Tick(time.Second) {
+		info, err := os.Stat(config.ConfigFileName)
+		if err != nil || !info.ModTime().After(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+		config.RLock()
+		watch := config.ConfigWatch
+		config.RUnlock()
+		if watch {
+			configReload <- "file watch"
+		}
+	}
+}
+
+// handleReloadConfigRequest handles reload config request.
+func handleReloadConfigRequest(cli *Client) ([]string, error) {
+	if !cli.User().Admin {
+		return nil, fmt.Errorf("You are not an admin")
+	}
+	keys, err := reloadConfig("user " + cli.User().ID())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to reload config: %v", err)
+	}
+	return keys, nil
+}
+
+// handleConfigCommand handles config command.
+func handleConfigCommand(cmd burn.Command) (int, string) {
+	if len(cmd.OptionArgs()) < 1 {
+		return 2, fmt.Sprintf("%s: no option args", configTool)
+	}
+	switch cmd.OptionArgs()[0] {
+	case "reload":
+		keys, err := reloadConfig("command")
+		if err != nil {
+			return 3, fmt.Sprintf("%s: %v", configTool, err)
+		}
+		return 0, strings.Join(keys, " ")
+	default:
+		return 2, fmt.Sprintf("%s: no such option: %s", configTool,
+			cmd.OptionArgs()[0])
+	}
+}
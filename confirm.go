@@ -1,7 +1,7 @@
 /*
  * confirm.go
  *
- * Copyright (C) 2020-2021 Dariusz Sikora <dev@isangeles.pl>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -22,37 +22,92 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/isangeles/flame/character"
 
+	"github.com/isangeles/fire/config"
 	"github.com/isangeles/fire/request"
 	"github.com/isangeles/fire/response"
 )
 
+// handleConfirm handles specified client confirmation of
+// pending request.
+func handleConfirm(con *clientConfirm) {
+	req, ok := pendingReqs[con.ID]
+	if !ok {
+		if con.Client != nil {
+			err := fmt.Sprintf("Pending request not found: %d", con.ID)
+			con.Client.Out <- response.Response{Error: []string{err}}
+		}
+		return
+	}
+	// Check permissions.
+	charID, charSerial := req.CharID, req.CharSerial
+	if con.Action == confirmCancel {
+		charID, charSerial = req.OwnerID, req.OwnerSerial
+	}
+	if con.Client != nil && !con.Client.User().Controls(charID, charSerial) {
+		return
+	}
+	switch con.Action {
+	case confirmAccept:
+		delete(pendingReqs, con.ID)
+		handleConfirmedRequest(req)
+	case confirmDecline:
+		delete(pendingReqs, con.ID)
+		notifyPendingReq(req, response.TradeDeclined)
+	case confirmCancel:
+		delete(pendingReqs, con.ID)
+		notifyPendingReq(req, response.TradeCanceled)
+	case confirmCounter:
+		err := handleCounterTrade(&req, con)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle counter-trade request: %v", err)
+			con.Client.Out <- response.Response{Error: []string{err}}
+			return
+		}
+		pendingReqs[con.ID] = req
+		notifyPendingReq(req, response.TradeCountered)
+	}
+}
+
 // handleConfirmedRequest handles specified request as confirmed.
 func handleConfirmedRequest(req charConfirmRequest) {
 	resp := response.Response{}
+	// Check if the request owner is still controlled by the client.
+	usr := req.Client.User()
+	if usr == nil || !usr.Logged || !usr.Controls(req.OwnerID, req.OwnerSerial) {
+		notifyPendingReq(req, response.TradeFailed)
+		return
+	}
 	for _, t := range req.Trade {
 		r, err := handleConfirmedTradeRequest(req.Client, t)
 		if err != nil {
 			err := fmt.Sprintf("Unable to handle trade request: %v", err)
 			resp.Error = append(resp.Error, err)
+			notifyPendingReq(req, response.TradeFailed)
 			continue
 		}
 		r.ID = req.ID
-		resp.TradeCompleted = append(resp.TradeCompleted, r)
-		// Send trade completed response to seller.
-		charResp := charResponse{
-			CharID:     t.Sell.ObjectToID,
-			CharSerial: t.Sell.ObjectToSerial,
+		// Send trade completed response to buyer and seller.
+		for _, char := range []response.Character{
+			{t.Buy.ObjectToID, t.Buy.ObjectToSerial},
+			{t.Sell.ObjectToID, t.Sell.ObjectToSerial},
+		} {
+			charResp := charResponse{
+				CharID:     char.ID,
+				CharSerial: char.Serial,
+			}
+			charResp.Response.TradeCompleted = append(charResp.Response.TradeCompleted,
+				r)
+			sendCharResp := func() { charResponses <- charResp }
+			go sendCharResp()
 		}
-		charResp.Response.TradeCompleted = append(charResp.Response.TradeCompleted,
-			r)
-		sendCharResp := func() { charResponses <- charResp }
-		go sendCharResp()
 	}
-	resp.Update = response.Update{Module: game.Data()}
-	req.Client.Out <- resp
+	if len(resp.Error) > 0 {
+		req.Client.Out <- resp
+	}
 }
 
 // handleConfirmedTradeRequest handles specified trade request as confirmed.
@@ -83,15 +138,26 @@ func handleConfirmedTradeRequest(cli *Client, req request.Trade) (resp response.
 			req.Sell.ObjectToSerial)
 		return
 	}
+	// Check range.
+	if !inRange(buyer, seller) {
+		err = fmt.Errorf("Objects are not in the minimal range")
+		return
+	}
 	// Trade items.
-	err = transferItems(seller, buyer, req.Buy.Items)
+	tx := newItemsTransaction()
+	err = tx.Add(seller, buyer, req.Buy.Items)
+	if err != nil {
+		err = fmt.Errorf("Invalid items to buy: %v", err)
+		return
+	}
+	err = tx.Add(buyer, seller, req.Sell.Items)
 	if err != nil {
-		err = fmt.Errorf("Unable to transfer items to buy: %v", err)
+		err = fmt.Errorf("Invalid items to sell: %v", err)
 		return
 	}
-	err = transferItems(buyer, seller, req.Sell.Items)
+	err = journalCommit(tradeEntry, tx)
 	if err != nil {
-		err = fmt.Errorf("Unable to transfer items to sell: %v", err)
+		err = fmt.Errorf("Unable to trade items: %v", err)
 		return
 	}
 	// Make response.
@@ -103,5 +169,84 @@ func handleConfirmedTradeRequest(cli *Client, req request.Trade) (resp response.
 		ItemsBuy:     req.Buy.Items,
 		ItemsSell:    req.Sell.Items,
 	}
+	game.fireEvent(tradeEvent, scriptTarget(buyer.ID(), buyer.Serial()),
+		scriptTarget(seller.ID(), seller.Serial()))
 	return
 }
+
+// handleCounterTrade modifies items of specified pending trade request
+// according to specified counter offer.
+// After modification, the request needs to be confirmed by the owner
+// of the request.
+func handleCounterTrade(req *charConfirmRequest, con *clientConfirm) error {
+	if len(req.Trade) < 1 {
+		return fmt.Errorf("Pending request is not a trade: %d", req.ID)
+	}
+	trade := req.Trade[0]
+	trade.Buy.Items = con.Counter.ItemsBuy
+	trade.Sell.Items = con.Counter.ItemsSell
+	req.Request = &request.Request{Trade: []request.Trade{trade}}
+	req.Client = con.Client
+	req.CharID, req.OwnerID = req.OwnerID, req.CharID
+	req.CharSerial, req.OwnerSerial = req.OwnerSerial, req.CharSerial
+	req.Time = time.Now()
+	return nil
+}
+
+// expirePendingReqs removes all pending requests older than
+// the trade timeout from the config package.
+func expirePendingReqs() {
+	if config.TradeTimeout < 1 {
+		return
+	}
+	timeout := time.Duration(config.TradeTimeout) * time.Millisecond
+	for id, req := range pendingReqs {
+		if time.Since(req.Time) < timeout {
+			continue
+		}
+		delete(pendingReqs, id)
+		notifyPendingReq(req, response.TradeExpired)
+	}
+}
+
+// notifyPendingReq sends trade status response with specified
+// status to the owner and the confirming character of specified
+// pending request.
+// For countered trades, the trade response is sent to the character
+// that needs to confirm the request.
+func notifyPendingReq(req charConfirmRequest, status string) {
+	statusResp := response.TradeStatus{ID: req.ID, Status: status}
+	for _, char := range []response.Character{
+		{req.OwnerID, req.OwnerSerial},
+		{req.CharID, req.CharSerial},
+	} {
+		charResp := charResponse{
+			CharID:     char.ID,
+			CharSerial: char.Serial,
+		}
+		charResp.Response.TradeStatus = append(charResp.Response.TradeStatus,
+			statusResp)
+		if status == response.TradeCountered && char.ID == req.CharID &&
+			char.Serial == req.CharSerial {
+			for _, t := range req.Trade {
+				charResp.Response.Trade = append(charResp.Response.Trade,
+					tradeResponse(req.ID, t))
+			}
+		}
+		sendCharResp := func() { charResponses <- charResp }
+		go sendCharResp()
+	}
+}
+
+// tradeResponse creates trade response for specified trade request.
+func tradeResponse(id int, req request.Trade) response.Trade {
+	return response.Trade{
+		ID:           id,
+		BuyerID:      req.Buy.ObjectToID,
+		BuyerSerial:  req.Buy.ObjectToSerial,
+		SellerID:     req.Sell.ObjectToID,
+		SellerSerial: req.Sell.ObjectToSerial,
+		ItemsBuy:     req.Buy.Items,
+		ItemsSell:    req.Sell.Items,
+	}
+}
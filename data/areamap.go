@@ -0,0 +1,63 @@
+/*
+ * areamap.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// Struct for map element of TMX file.
+type tmxMap struct {
+	Width      int `xml:"width,attr"`
+	Height     int `xml:"height,attr"`
+	TileWidth  int `xml:"tilewidth,attr"`
+	TileHeight int `xml:"tileheight,attr"`
+}
+
+// ImportAreaMap imports tile grid of the area map from TMX
+// file with specified path.
+func ImportAreaMap(path string) (res.AreaMapData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return res.AreaMapData{}, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	var m tmxMap
+	err = xml.NewDecoder(file).Decode(&m)
+	if err != nil {
+		return res.AreaMapData{}, fmt.Errorf("unable to decode map: %v", err)
+	}
+	if m.Width < 1 || m.Height < 1 || m.TileWidth < 1 || m.TileHeight < 1 {
+		return res.AreaMapData{}, fmt.Errorf("invalid map size: %dx%d, tile: %dx%d",
+			m.Width, m.Height, m.TileWidth, m.TileHeight)
+	}
+	data := res.AreaMapData{
+		Width:      m.Width,
+		Height:     m.Height,
+		TileWidth:  m.TileWidth,
+		TileHeight: m.TileHeight,
+	}
+	return data, nil
+}
@@ -0,0 +1,139 @@
+/*
+ * chat.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/isangeles/flame/data/text"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// ImportChatFilter imports list of filtered chat words
+// from file with specified path.
+// Returns no words and no error if the file does not exist.
+func ImportChatFilter(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	conf, err := text.UnmarshalConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal chat filter: %v", err)
+	}
+	return conf["words"], nil
+}
+
+// LoadChatHistory loads the newest chat records, up to specified
+// maximal number, from the chat history file with specified path.
+// Returns no records and no error if the file does not exist.
+func LoadChatHistory(path string, max int) ([]res.ChatRecordData, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	var records []res.ChatRecordData
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record res.ChatRecordData
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal record: %v", err)
+		}
+		records = append(records, record)
+		if max > 0 && len(records) > max {
+			records = records[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read file: %v", err)
+	}
+	return records, nil
+}
+
+// SaveChatHistory saves specified records to the chat history
+// file with specified path, replacing all previous records.
+func SaveChatHistory(path string, records []res.ChatRecordData) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create history directory: %v", err)
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("unable to create file: %v", err)
+	}
+	defer file.Close()
+	write := bufio.NewWriter(file)
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("unable to marshal record: %v", err)
+		}
+		write.Write(append(line, '\n'))
+	}
+	err = write.Flush()
+	if err != nil {
+		return fmt.Errorf("unable to write records: %v", err)
+	}
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("unable to sync file: %v", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// AppendChatHistory appends specified record to the chat history
+// file with specified path.
+func AppendChatHistory(path string, record res.ChatRecordData) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to marshal record: %v", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create history directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("unable to write record: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+ * guilds.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+const guildFileExt = ".json"
+
+// LoadGuilds loads all guilds from directory with specified path.
+// Returns no guilds and no error if the directory does not exist.
+func LoadGuilds(path string) ([]res.GuildData, error) {
+	files, err := ioutil.ReadDir(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %v", err)
+	}
+	var guilds []res.GuildData
+	for _, info := range files {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), guildFileExt) {
+			continue
+		}
+		file, err := ioutil.ReadFile(filepath.Join(path, info.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read guild file: %s: %v",
+				info.Name(), err)
+		}
+		var guild res.GuildData
+		err = json.Unmarshal(file, &guild)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal guild: %s: %v",
+				info.Name(), err)
+		}
+		guilds = append(guilds, guild)
+	}
+	return guilds, nil
+}
+
+// SaveGuild saves specified guild in directory with specified path.
+func SaveGuild(path string, guild res.GuildData) error {
+	file, err := json.Marshal(guild)
+	if err != nil {
+		return fmt.Errorf("unable to marshal guild: %v", err)
+	}
+	err = os.MkdirAll(path, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create guilds directory: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(path, guild.ID+guildFileExt), file, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write file: %v", err)
+	}
+	return nil
+}
+
+// RemoveGuild removes guild with specified ID from directory with
+// specified path.
+func RemoveGuild(path, id string) error {
+	err := os.Remove(filepath.Join(path, id+guildFileExt))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove file: %v", err)
+	}
+	return nil
+}
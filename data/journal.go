@@ -0,0 +1,120 @@
+/*
+ * journal.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// LoadJournal loads all entries from the journal file with
+// specified path.
+// Returns no entries and no error if the file does not exist.
+func LoadJournal(path string) ([]res.JournalEntryData, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	var entries []res.JournalEntryData
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var entry res.JournalEntryData
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			// Last entry could be written partially before the crash.
+			break
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read file: %v", err)
+	}
+	return entries, nil
+}
+
+// AppendJournal appends specified entry to the journal file
+// with specified path.
+// The file is synced to the disk before return.
+func AppendJournal(path string, entry res.JournalEntryData) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("unable to marshal entry: %v", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create journal directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("unable to write entry: %v", err)
+	}
+	return file.Sync()
+}
+
+// SaveJournal replaces content of the journal file with specified
+// path with specified entries.
+// The new content is written to the temporary file first, which then
+// replaces the journal file.
+func SaveJournal(path string, entries []res.JournalEntryData) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create journal directory: %v", err)
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("unable to create file: %v", err)
+	}
+	defer file.Close()
+	write := bufio.NewWriter(file)
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("unable to marshal entry: %v", err)
+		}
+		write.Write(append(line, '\n'))
+	}
+	err = write.Flush()
+	if err != nil {
+		return fmt.Errorf("unable to write entries: %v", err)
+	}
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("unable to sync file: %v", err)
+	}
+	return os.Rename(tmpPath, path)
+}
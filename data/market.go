@@ -0,0 +1,68 @@
+/*
+ * market.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// LoadMarket loads marketplace data from file with
+// specified path.
+// Returns empty data if the file does not exist.
+func LoadMarket(path string) (data res.MarketData, err error) {
+	file, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return data, fmt.Errorf("unable to read file: %v", err)
+	}
+	err = json.Unmarshal(file, &data)
+	if err != nil {
+		return data, fmt.Errorf("unable to unmarshal data: %v", err)
+	}
+	return data, nil
+}
+
+// SaveMarket saves specified marketplace data in file
+// with specified path.
+func SaveMarket(path string, data res.MarketData) error {
+	file, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("unable to marshal data: %v", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create market directory: %v", err)
+	}
+	err = ioutil.WriteFile(path, file, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write file: %v", err)
+	}
+	return nil
+}
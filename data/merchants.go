@@ -0,0 +1,97 @@
+/*
+ * merchants.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/isangeles/flame/data/text"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// ImportMerchants imports all merchants from directory
+// with specified path.
+func ImportMerchants(path string) ([]res.MerchantData, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %v", err)
+	}
+	merchants := make([]res.MerchantData, 0)
+	for _, info := range files {
+		if info.IsDir() {
+			continue
+		}
+		merchant, err := ImportMerchant(filepath.Join(path, info.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to import merchant: %s: %v",
+				info.Name(), err)
+		}
+		merchants = append(merchants, merchant)
+	}
+	return merchants, nil
+}
+
+// ImportMerchant imports merchant from file with specified path.
+func ImportMerchant(path string) (data res.MerchantData, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return data, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	conf, err := text.UnmarshalConfig(file)
+	if err != nil {
+		return data, fmt.Errorf("unable to unmarshal merchant: %v", err)
+	}
+	data.ID = filepath.Base(path)
+	data.Chars = conf["chars"]
+	data.Stock = conf["stock"]
+	data.SellMod, data.BuyMod = 1, 1
+	if len(conf["sell-mod"]) > 0 {
+		data.SellMod, err = strconv.ParseFloat(conf["sell-mod"][0], 64)
+		if err != nil {
+			return data, fmt.Errorf("invalid sell mod: %v", err)
+		}
+	}
+	if len(conf["buy-mod"]) > 0 {
+		data.BuyMod, err = strconv.ParseFloat(conf["buy-mod"][0], 64)
+		if err != nil {
+			return data, fmt.Errorf("invalid buy mod: %v", err)
+		}
+	}
+	if len(conf["stock-size"]) > 0 {
+		data.StockSize, err = strconv.Atoi(conf["stock-size"][0])
+		if err != nil {
+			return data, fmt.Errorf("invalid stock size: %v", err)
+		}
+	}
+	if len(conf["restock-time"]) > 0 {
+		data.RestockTime, err = strconv.ParseInt(conf["restock-time"][0], 10, 64)
+		if err != nil {
+			return data, fmt.Errorf("invalid restock time: %v", err)
+		}
+	}
+	return data, nil
+}
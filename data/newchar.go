@@ -0,0 +1,76 @@
+/*
+ * newchar.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/isangeles/flame/data/text"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// ImportNewCharPolicy imports new character policy from
+// file with specified path.
+func ImportNewCharPolicy(path string) (data res.NewCharPolicyData, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return data, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	conf, err := text.UnmarshalConfig(file)
+	if err != nil {
+		return data, fmt.Errorf("unable to unmarshal policy: %v", err)
+	}
+	data.Races = conf["races"]
+	data.Sexes = conf["sexes"]
+	data.Alignments = conf["alignments"]
+	data.NameBlacklist = conf["name-blacklist"]
+	if len(conf["name-pattern"]) > 0 {
+		data.NamePattern = conf["name-pattern"][0]
+		_, err = regexp.Compile(data.NamePattern)
+		if err != nil {
+			return data, fmt.Errorf("invalid name pattern: %v", err)
+		}
+	}
+	if len(conf["attrs-budget"]) > 0 {
+		data.AttrsBudget, err = strconv.Atoi(conf["attrs-budget"][0])
+		if err != nil {
+			return data, fmt.Errorf("invalid attributes budget: %v", err)
+		}
+	}
+	if len(conf["name-min-len"]) > 0 {
+		data.NameMinLen, err = strconv.Atoi(conf["name-min-len"][0])
+		if err != nil {
+			return data, fmt.Errorf("invalid name min length: %v", err)
+		}
+	}
+	if len(conf["name-max-len"]) > 0 {
+		data.NameMaxLen, err = strconv.Atoi(conf["name-max-len"][0])
+		if err != nil {
+			return data, fmt.Errorf("invalid name max length: %v", err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,32 @@
+/*
+ * chat.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package res
+
+// Struct for chat history record data.
+type ChatRecordData struct {
+	UserID       string `json:"user-id"`
+	ObjectID     string `json:"object-id"`
+	ObjectSerial string `json:"object-serial"`
+	Channel      string `json:"channel"`
+	To           string `json:"to"`
+	Message      string `json:"message"`
+	Time         int64  `json:"time"`
+}
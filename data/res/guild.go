@@ -0,0 +1,55 @@
+/*
+ * guild.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package res
+
+// Struct for guild data.
+type GuildData struct {
+	ID      string            `json:"id"`
+	Motd    string            `json:"motd"`
+	Ranks   []GuildRankData   `json:"ranks"`
+	Members []GuildMemberData `json:"members"`
+	Bank    []GuildItemData   `json:"bank"`
+}
+
+// Struct for guild rank data.
+type GuildRankData struct {
+	ID        string `json:"id"`
+	Invite    bool   `json:"invite"`
+	Kick      bool   `json:"kick"`
+	Promote   bool   `json:"promote"`
+	Motd      bool   `json:"motd"`
+	BankLimit int    `json:"bank-limit"`
+}
+
+// Struct for guild member data.
+type GuildMemberData struct {
+	UserID      string `json:"user-id"`
+	Rank        string `json:"rank"`
+	Joined      int64  `json:"joined"`
+	Withdrawn   int    `json:"withdrawn"`
+	WithdrawDay int64  `json:"withdraw-day"`
+}
+
+// Struct for data of item in the guild bank.
+type GuildItemData struct {
+	ID     string `json:"id"`
+	Serial string `json:"serial"`
+}
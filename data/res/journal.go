@@ -0,0 +1,51 @@
+/*
+ * journal.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package res
+
+import (
+	flameres "github.com/isangeles/flame/data/res"
+)
+
+// Struct for journal entry data.
+type JournalEntryData struct {
+	Seq       int64                   `json:"seq"`
+	Type      string                  `json:"type"`
+	Time      int64                   `json:"time"`
+	Save      string                  `json:"save,omitempty"`
+	SaveTime  int64                   `json:"save-time,omitempty"`
+	UserID    string                  `json:"user-id,omitempty"`
+	Name      string                  `json:"name,omitempty"`
+	Char      *flameres.CharacterData `json:"char,omitempty"`
+	CharID    string                  `json:"char-id,omitempty"`
+	Serial    string                  `json:"serial,omitempty"`
+	Chapter   string                  `json:"chapter,omitempty"`
+	Transfers []JournalTransferData   `json:"transfers,omitempty"`
+	Aborted   int64                   `json:"aborted,omitempty"`
+}
+
+// Struct for data of items transfer in journal entry.
+type JournalTransferData struct {
+	FromID     string              `json:"from-id"`
+	FromSerial string              `json:"from-serial"`
+	ToID       string              `json:"to-id"`
+	ToSerial   string              `json:"to-serial"`
+	Items      map[string][]string `json:"items"`
+}
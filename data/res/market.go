@@ -0,0 +1,49 @@
+/*
+ * market.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package res
+
+// Struct for marketplace data.
+type MarketData struct {
+	LastID   int                 `json:"last-id"`
+	Listings []MarketListingData `json:"listings"`
+	Returns  []MarketReturnData  `json:"returns"`
+}
+
+// Struct for marketplace listing data.
+type MarketListingData struct {
+	ID           int    `json:"id"`
+	UserID       string `json:"user-id"`
+	SellerID     string `json:"seller-id"`
+	SellerSerial string `json:"seller-serial"`
+	ItemID       string `json:"item-id"`
+	ItemSerial   string `json:"item-serial"`
+	Price        int    `json:"price"`
+	Expires      int64  `json:"expires"`
+}
+
+// Struct for data of item or currency waiting to
+// be collected from the marketplace by the user.
+type MarketReturnData struct {
+	UserID     string `json:"user-id"`
+	ItemID     string `json:"item-id"`
+	ItemSerial string `json:"item-serial"`
+	Currency   int    `json:"currency"`
+}
@@ -1,7 +1,7 @@
 /*
  * user.go
  *
- * Copyright (C) 2020-2021 Dariusz Sikora <dev@isangeles.pl>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -20,10 +20,40 @@
 
 package res
 
+import (
+	flameres "github.com/isangeles/flame/data/res"
+)
+
 // Struct for user data.
 type UserData struct {
-	ID        string
-	Pass      string
-	Admin     bool
-	CharFlags []string
+	ID           string
+	Pass         string
+	Admin        bool
+	MaxChars     int
+	MutedUntil   int64
+	CharFlags    []string
+	Friends      []string
+	Ignored      []string
+	OfflineChars []OfflineCharData
+	SavedChars   []SavedCharData
+	Mail         []MailData
+}
+
+// Struct for data of user character
+// removed from the game world.
+type OfflineCharData struct {
+	Area string                 `json:"area"`
+	PosX float64                `json:"pos-x"`
+	PosY float64                `json:"pos-y"`
+	Char flameres.CharacterData `json:"char"`
+}
+
+// Struct for data of user character saved
+// independently of the game world.
+type SavedCharData struct {
+	Time int64                  `json:"time"`
+	Area string                 `json:"area"`
+	PosX float64                `json:"pos-x"`
+	PosY float64                `json:"pos-y"`
+	Char flameres.CharacterData `json:"char"`
 }
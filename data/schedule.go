@@ -0,0 +1,79 @@
+/*
+ * schedule.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/isangeles/flame/data/text"
+
+	"github.com/isangeles/fire/data/res"
+)
+
+// Schedule types.
+const (
+	scheduleEvery = "every"
+	scheduleAt    = "at"
+)
+
+// ImportSchedule imports scheduled scripts from schedule file
+// with specified path.
+func ImportSchedule(path string) ([]res.ScheduleData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file: %v", err)
+	}
+	defer file.Close()
+	conf, err := text.UnmarshalConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal schedule: %v", err)
+	}
+	schedule := make([]res.ScheduleData, 0)
+	for id, values := range conf {
+		if len(values) < 3 {
+			return nil, fmt.Errorf("invalid schedule entry: %s", id)
+		}
+		data := res.ScheduleData{ID: id, Script: values[0]}
+		switch values[1] {
+		case scheduleEvery:
+			data.Interval, err = strconv.ParseInt(values[2], 10, 64)
+			if err != nil || data.Interval < 1 {
+				return nil, fmt.Errorf("invalid interval: %s: %s", id,
+					values[2])
+			}
+		case scheduleAt:
+			at, err := time.Parse("15:04", values[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid time: %s: %v", id, err)
+			}
+			data.Daily = true
+			data.Hour, data.Minute = at.Hour(), at.Minute()
+		default:
+			return nil, fmt.Errorf("invalid schedule type: %s: %s", id,
+				values[1])
+		}
+		schedule = append(schedule, data)
+	}
+	return schedule, nil
+}
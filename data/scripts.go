@@ -1,7 +1,7 @@
 /*
  * scripts.go
  *
- * Copyright (C) 2021 Dariusz Sikora <dev@isangeles.pl>
+ * Copyright (C) 2021-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -23,10 +23,10 @@ package data
 import (
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
-	"log"
 
 	"github.com/isangeles/burn/ash"
 )
@@ -75,3 +75,38 @@ func ImportScript(path string) (*ash.Script, error) {
 	}
 	return script, nil
 }
+
+// ImportScriptSources imports texts of all scripts from directory
+// with specified path.
+// Returns map with script file paths as keys and script texts
+// as values.
+func ImportScriptSources(path string) (map[string]string, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %v", err)
+	}
+	sources := make(map[string]string)
+	for _, info := range files {
+		if !strings.HasSuffix(info.Name(), ashScriptExt) {
+			continue
+		}
+		scriptPath := filepath.Join(path, info.Name())
+		text, err := ImportScriptSource(scriptPath)
+		if err != nil {
+			log.Printf("Data: unable to retrieve script: %v", err)
+			continue
+		}
+		sources[scriptPath] = text
+	}
+	return sources, nil
+}
+
+// ImportScriptSource imports text of script from file with
+// specified path.
+func ImportScriptSource(path string) (string, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read file: %v", err)
+	}
+	return string(text), nil
+}
@@ -1,7 +1,7 @@
 /*
  * users.go
  *
- * Copyright (C) 2020-2025 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -23,11 +23,14 @@ package data
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/isangeles/flame/data/text"
 
@@ -36,7 +39,11 @@ import (
 )
 
 const (
-	userConfFile = ".user"
+	userConfFile     = ".user"
+	offlineCharsFile = "offline-chars.json"
+	mailFile         = "mail.json"
+	savedCharsDir    = "chars"
+	savedCharExt     = ".json"
 )
 
 var (
@@ -49,6 +56,14 @@ func User(id string) *user.User {
 	return users[id]
 }
 
+// Users returns all loaded users.
+func Users() (u []*user.User) {
+	for _, usr := range users {
+		u = append(u, usr)
+	}
+	return
+}
+
 // LoadUsers loads all users from directory
 // with specified path.
 func LoadUsers(path string) error {
@@ -86,6 +101,12 @@ func SaveUsers(path string) error {
 	return nil
 }
 
+// SaveUser saves specified user under users directory
+// with specified path.
+func SaveUser(path string, user *user.User) error {
+	return saveUser(filepath.Join(path, user.ID()), user)
+}
+
 // loadUser loads user from directory with
 // specified path.
 func loadUser(path string) (*user.User, error) {
@@ -106,7 +127,40 @@ func loadUser(path string) (*user.User, error) {
 	if len(userConf["admin"]) > 0 {
 		userData.Admin = userConf["admin"][0] == "true"
 	}
+	if len(userConf["max-chars"]) > 0 {
+		maxChars, err := strconv.Atoi(userConf["max-chars"][0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid max chars value: %v", err)
+		}
+		userData.MaxChars = maxChars
+	}
+	if len(userConf["muted-until"]) > 0 {
+		mutedUntil, err := strconv.ParseInt(userConf["muted-until"][0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid muted until value: %v", err)
+		}
+		userData.MutedUntil = mutedUntil
+	}
 	userData.CharFlags = userConf["char-flags"]
+	userData.Friends = userConf["friends"]
+	userData.Ignored = userConf["ignored"]
+	offlineChars, err := loadOfflineChars(filepath.Join(path, offlineCharsFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load offline characters: %v",
+			err)
+	}
+	userData.OfflineChars = offlineChars
+	savedChars, err := loadSavedChars(filepath.Join(path, savedCharsDir))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load saved characters: %v",
+			err)
+	}
+	userData.SavedChars = savedChars
+	mail, err := loadMail(filepath.Join(path, mailFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load mail: %v", err)
+	}
+	userData.Mail = mail
 	return user.New(userData), nil
 }
 
@@ -119,9 +173,21 @@ func saveUser(path string, user *user.User) error {
 	conf := make(map[string][]string)
 	conf["pass"] = []string{user.Pass()}
 	conf["admin"] = []string{fmt.Sprintf("%v", user.Admin)}
+	if user.MaxChars > 0 {
+		conf["max-chars"] = []string{fmt.Sprintf("%d", user.MaxChars)}
+	}
+	if user.Muted() {
+		conf["muted-until"] = []string{fmt.Sprintf("%d", user.MutedUntil)}
+	}
 	for _, f := range user.CharFlags() {
 		conf["char-flags"] = append(conf["char-flags"], string(f))
 	}
+	if len(user.Friends()) > 0 {
+		conf["friends"] = user.Friends()
+	}
+	if len(user.Ignored()) > 0 {
+		conf["ignored"] = user.Ignored()
+	}
 	confText := text.MarshalConfig(conf)
 	confPath := filepath.Join(path, userConfFile)
 	confFile, err := os.Create(confPath)
@@ -132,5 +198,170 @@ func saveUser(path string, user *user.User) error {
 	writer := bufio.NewWriter(confFile)
 	writer.WriteString(confText)
 	writer.Flush()
+	err = saveOfflineChars(filepath.Join(path, offlineCharsFile), user.OfflineChars())
+	if err != nil {
+		return fmt.Errorf("unable to save offline characters: %v", err)
+	}
+	err = saveSavedChars(filepath.Join(path, savedCharsDir), user.SavedChars())
+	if err != nil {
+		return fmt.Errorf("unable to save saved characters: %v", err)
+	}
+	err = saveMail(filepath.Join(path, mailFile), user.Mail())
+	if err != nil {
+		return fmt.Errorf("unable to save mail: %v", err)
+	}
+	return nil
+}
+
+// loadOfflineChars loads offline characters data from file
+// with specified path.
+// Returns no data and no error if the file does not exist.
+func loadOfflineChars(path string) ([]res.OfflineCharData, error) {
+	file, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %v", err)
+	}
+	var chars []res.OfflineCharData
+	err = json.Unmarshal(file, &chars)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal data: %v", err)
+	}
+	return chars, nil
+}
+
+// saveOfflineChars saves specified offline characters data
+// in file with specified path.
+// Removes the file if there is no data to save.
+func saveOfflineChars(path string, chars []res.OfflineCharData) error {
+	if len(chars) < 1 {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove file: %v", err)
+		}
+		return nil
+	}
+	file, err := json.Marshal(chars)
+	if err != nil {
+		return fmt.Errorf("unable to marshal data: %v", err)
+	}
+	err = ioutil.WriteFile(path, file, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write file: %v", err)
+	}
+	return nil
+}
+
+// loadSavedChars loads saved characters data from all files
+// in the directory with specified path.
+// Returns no data and no error if the directory does not exist.
+func loadSavedChars(path string) ([]res.SavedCharData, error) {
+	files, err := ioutil.ReadDir(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dir: %v", err)
+	}
+	var chars []res.SavedCharData
+	for _, info := range files {
+		if info.IsDir() || filepath.Ext(info.Name()) != savedCharExt {
+			continue
+		}
+		file, err := ioutil.ReadFile(filepath.Join(path, info.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file: %s: %v", info.Name(), err)
+		}
+		var char res.SavedCharData
+		err = json.Unmarshal(file, &char)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal data: %s: %v", info.Name(), err)
+		}
+		chars = append(chars, char)
+	}
+	return chars, nil
+}
+
+// saveSavedChars saves specified saved characters data in
+// the directory with specified path, each character in a separate
+// file.
+// Removes files of characters without data to save.
+func saveSavedChars(path string, chars []res.SavedCharData) error {
+	files := make(map[string]bool)
+	if len(chars) > 0 {
+		err := os.MkdirAll(path, 0755)
+		if err != nil {
+			return fmt.Errorf("unable to create directory: %v", err)
+		}
+	}
+	for _, c := range chars {
+		file, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("unable to marshal data: %v", err)
+		}
+		name := c.Char.ID + "#" + c.Char.Serial + savedCharExt
+		err = ioutil.WriteFile(filepath.Join(path, name), file, 0644)
+		if err != nil {
+			return fmt.Errorf("unable to write file: %v", err)
+		}
+		files[name] = true
+	}
+	infos, err := ioutil.ReadDir(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read dir: %v", err)
+	}
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != savedCharExt || files[info.Name()] {
+			continue
+		}
+		err := os.Remove(filepath.Join(path, info.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to remove file: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadMail loads mail data from file with specified path.
+// Returns no data and no error if the file does not exist.
+func loadMail(path string) ([]res.MailData, error) {
+	file, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %v", err)
+	}
+	var mail []res.MailData
+	err = json.Unmarshal(file, &mail)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal data: %v", err)
+	}
+	return mail, nil
+}
+
+// saveMail saves specified mail data in file with specified path.
+// Removes the file if there is no data to save.
+func saveMail(path string, mail []res.MailData) error {
+	if len(mail) < 1 {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove file: %v", err)
+		}
+		return nil
+	}
+	file, err := json.Marshal(mail)
+	if err != nil {
+		return fmt.Errorf("unable to marshal data: %v", err)
+	}
+	err = ioutil.WriteFile(path, file, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to write file: %v", err)
+	}
 	return nil
 }
@@ -0,0 +1,73 @@
+/*
+ * escrow.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	flameres "github.com/isangeles/flame/data/res"
+	"github.com/isangeles/flame/item"
+)
+
+var escrow = newItemsEscrow()
+
+// Struct for server items escrow.
+// Escrow holds items removed from the game world,
+// e.g. items listed on the marketplace or attached
+// to mail.
+type itemsEscrow struct {
+	items map[string]item.Item
+}
+
+// newItemsEscrow creates new items escrow.
+func newItemsEscrow() *itemsEscrow {
+	e := itemsEscrow{items: make(map[string]item.Item)}
+	return &e
+}
+
+// Hold adds specified item to the escrow.
+func (e *itemsEscrow) Hold(it item.Item) {
+	e.items[it.ID()+it.Serial()] = it
+}
+
+// Item returns item with specified ID and serial from the escrow.
+// Item is created from the resources if it's not present in the escrow,
+// e.g. after the server restart.
+func (e *itemsEscrow) Item(id, serial string) (item.Item, error) {
+	it := e.items[id+serial]
+	if it != nil {
+		return it, nil
+	}
+	data := flameres.Item(id)
+	if data == nil {
+		return nil, fmt.Errorf("Item data not found: %s", id)
+	}
+	it = item.New(data)
+	it.SetSerial(serial)
+	e.items[id+serial] = it
+	return it, nil
+}
+
+// Release removes item with specified ID and serial
+// from the escrow.
+func (e *itemsEscrow) Release(id, serial string) {
+	delete(e.items, id+serial)
+}
@@ -0,0 +1,252 @@
+/*
+ * events.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isangeles/burn"
+
+	"github.com/isangeles/flame/character"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+)
+
+// Names of server events handled by event scripts.
+const (
+	loginEvent         = "login"
+	logoutEvent        = "logout"
+	newCharEvent       = "new-char"
+	chatEvent          = "chat"
+	tradeEvent         = "trade"
+	areaEnterEvent     = "area-enter"
+	chapterChangeEvent = "chapter-change"
+	charDeathEvent     = "char-death"
+)
+
+// Struct for ash scripts handling server events.
+type serverEvents struct {
+	mutex      sync.RWMutex
+	scripts    map[string]map[string]string
+	loaded     time.Time
+	stateMutex sync.Mutex
+	areas      map[string]string
+	alive      map[string]bool
+	occupied   map[string]bool
+}
+
+// newServerEvents creates new server events handler.
+func newServerEvents() *serverEvents {
+	se := serverEvents{
+		scripts:  make(map[string]map[string]string),
+		areas:    make(map[string]string),
+		alive:    make(map[string]bool),
+		occupied: make(map[string]bool),
+	}
+	return &se
+}
+
+// Scripts returns texts of all scripts registered for
+// specified event, mapped by script file paths, and
+// the time when the scripts were loaded.
+func (se *serverEvents) Scripts(event string) (map[string]string, time.Time) {
+	se.mutex.RLock()
+	defer se.mutex.RUnlock()
+	return se.scripts[event], se.loaded
+}
+
+// SetScripts sets specified scripts as handlers for all
+// events.
+// Scripts for each event are mapped by script file paths.
+func (se *serverEvents) SetScripts(scripts map[string]map[string]string) {
+	se.mutex.Lock()
+	defer se.mutex.Unlock()
+	se.scripts = scripts
+	se.loaded = time.Now()
+}
+
+// Check compares areas and live status of specified characters
+// with the state from the last check and triggers area-enter and
+// char-death events for all detected changes.
+// Characters not present in the last check are only tracked.
+// Also starts scripts of areas entered by the first active character
+// and stops scripts of areas left by the last one.
+// Should be called by the server update loop, as the characters of
+// users and the event scripts are modified by the handlers of client
+// requests.
+func (se *serverEvents) Check(g *Game, chars []*character.Character) {
+	se.stateMutex.Lock()
+	defer se.stateMutex.Unlock()
+	areas := make(map[string]string)
+	alive := make(map[string]bool)
+	occupied := make(map[string]bool)
+	for _, c := range chars {
+		key := c.ID() + c.Serial()
+		alive[key] = c.Live()
+		if lastAlive, ok := se.alive[key]; ok && lastAlive && !c.Live() {
+			g.fireEvent(charDeathEvent, scriptTarget(c.ID(), c.Serial()))
+		}
+		area := g.Chapter().ObjectArea(c)
+		if area == nil {
+			continue
+		}
+		areas[key] = area.ID()
+		if !c.HasFlag(inactiveCharFlag) {
+			occupied[area.ID()] = true
+		}
+		if last, ok := se.areas[key]; ok && last != area.ID() {
+			g.fireEvent(areaEnterEvent, scriptTarget(c.ID(), c.Serial()),
+				area.ID())
+		}
+	}
+	se.areas = areas
+	se.alive = alive
+	for id := range occupied {
+		if se.occupied[id] {
+			continue
+		}
+		err := g.runAreaScripts(id)
+		if err != nil {
+			log.Printf("Game: unable to run area scripts: %s: %v", id, err)
+		}
+	}
+	for id := range se.occupied {
+		if !occupied[id] {
+			g.stopAreaScripts(id)
+		}
+	}
+	se.occupied = occupied
+}
+
+// ResetAreas clears tracked areas of characters.
+// Should be called on chapter change, as areas from the
+// previous chapter are no longer available.
+func (se *serverEvents) ResetAreas() {
+	se.stateMutex.Lock()
+	defer se.stateMutex.Unlock()
+	se.areas = make(map[string]string)
+	se.occupied = make(map[string]bool)
+}
+
+// OccupiedAreas returns IDs of areas with active characters
+// from the last check.
+func (se *serverEvents) OccupiedAreas() (areas []string) {
+	se.stateMutex.Lock()
+	defer se.stateMutex.Unlock()
+	for id := range se.occupied {
+		areas = append(areas, id)
+	}
+	return
+}
+
+// fireEvent runs all scripts registered for specified event.
+// Event scripts are stopped after the first pass through their
+// blocks.
+// Specified event data is passed to the scripts as arguments,
+// available in the script text as @1, @2, etc.
+func (g *Game) fireEvent(event string, args ...string) {
+	scripts, loaded := g.events.Scripts(event)
+	for path, text := range scripts {
+		scriptArgs := append([]string{event}, args...)
+		script, err := newGameScript(g.scriptName(path), path, text, loaded,
+			scriptArgs...)
+		if err != nil {
+			log.Printf("Game: unable to create %s event script: %s: %v",
+				event, path, err)
+			continue
+		}
+		script.once = true
+		go g.runScript(script)
+	}
+}
+
+// loadEventScripts loads event scripts from the module server
+// directory.
+// Scripts for each event are stored in the separate directory
+// named after the event.
+func (g *Game) loadEventScripts() error {
+	path := filepath.Join(g.Conf().Path, config.ModuleServerPath, "events")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		g.events.SetScripts(make(map[string]map[string]string))
+		return nil
+	}
+	dirs, err := ioutil.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("unable to read events dir: %v", err)
+	}
+	events := make(map[string]map[string]string)
+	for _, info := range dirs {
+		if !info.IsDir() {
+			continue
+		}
+		scripts, err := data.ImportScriptSources(filepath.Join(path, info.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to import %s event scripts: %v",
+				info.Name(), err)
+		}
+		events[info.Name()] = scripts
+	}
+	g.events.SetScripts(events)
+	return nil
+}
+
+// scriptTarget returns burn target ID for object with
+// specified ID and serial.
+func scriptTarget(id, serial string) string {
+	return id + burn.IDSerialSep + serial
+}
+
+// scriptText returns specified text as an quoted script argument,
+// with all characters that could alter the script syntax removed.
+// Words starting with the option or pipe prefix are removed too,
+// as script commands are split on white spaces, regardless of
+// the quotes.
+func scriptText(text string) string {
+	var words []string
+	for _, w := range strings.Fields(scriptSafeText(text)) {
+		if strings.HasPrefix(w, "-") || strings.HasPrefix(w, "|") {
+			continue
+		}
+		words = append(words, w)
+	}
+	return "\"" + strings.Join(words, " ") + "\""
+}
+
+// scriptSafeText returns specified text with all characters
+// that could alter the script syntax removed.
+func scriptSafeText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '\'', ';', '{', '}', '@', '\n', '\r':
+			return -1
+		}
+		return r
+	}, text)
+}
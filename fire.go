@@ -26,11 +26,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/gorilla/websocket"
 
+	flamedata "github.com/isangeles/flame/data"
 	flameres "github.com/isangeles/flame/data/res"
 	flamelog "github.com/isangeles/flame/log"
 	"github.com/isangeles/flame/serial"
@@ -50,10 +51,12 @@ var (
 	leave           = make(chan string)
 	requests        = make(chan clientRequest)
 	charResponses   = make(chan charResponse)
+	userResponses   = make(chan userResponse)
 	confirmRequests = make(chan charConfirmRequest)
 	confirmed       = make(chan *clientConfirm)
 	load            = make(chan response.Load)
 	pendingReqs     = make(map[int]charConfirmRequest)
+	lastPendingID   = 0
 	close           bool
 )
 
@@ -67,15 +70,30 @@ type clientRequest struct {
 // confirm.
 type charConfirmRequest struct {
 	clientRequest
-	CharID     string
-	CharSerial string
-	ID         int
+	CharID      string
+	CharSerial  string
+	OwnerID     string
+	OwnerSerial string
+	ID          int
+	Time        time.Time
 }
 
+// Type for client confirmation action.
+type confirmAction int
+
+const (
+	confirmAccept confirmAction = iota
+	confirmDecline
+	confirmCancel
+	confirmCounter
+)
+
 // Struct for client confirmation.
 type clientConfirm struct {
-	ID     int
-	Client *Client
+	ID      int
+	Client  *Client
+	Action  confirmAction
+	Counter request.CounterTrade
 }
 
 // Struct with response for the owner of game
@@ -86,6 +104,15 @@ type charResponse struct {
 	CharSerial string
 }
 
+// Struct with response for clients logged as user
+// with specified ID.
+// Empty user ID means that the response is for all
+// logged clients.
+type userResponse struct {
+	response.Response
+	UserID string
+}
+
 // Main function.
 func main() {
 	flamelog.PrintStdOut = true
@@ -100,31 +127,79 @@ func main() {
 	if err != nil {
 		log.Printf("Unable to load users: %v", err)
 	}
+	marketData, err := data.LoadMarket(config.MarketFile)
+	if err != nil {
+		log.Printf("Unable to load market: %v", err)
+	}
+	marketplace = newMarket(marketData)
+	err = loadChatFilter()
+	if err != nil {
+		log.Printf("Unable to load chat filter: %v", err)
+	}
+	chatRecords, err := data.LoadChatHistory(config.ChatHistoryFile, config.ChatHistorySize)
+	if err != nil {
+		log.Printf("Unable to load chat history: %v", err)
+	}
+	chatHistory = newChatLog(config.ChatHistoryFile, chatRecords...)
+	if err == nil && config.ChatHistorySize > 0 {
+		err = chatHistory.Compact()
+		if err != nil {
+			log.Printf("Unable to compact chat history: %v", err)
+		}
+	}
+	err = loadGuilds()
+	if err != nil {
+		log.Printf("Unable to load guilds: %v", err)
+	}
 	if len(config.Module) < 1 {
 		panic(fmt.Errorf("No game module configurated"))
 	}
-	modData, err := importModule(config.ModulePath())
+	journalEntries, err := data.LoadJournal(config.JournalFile)
+	if err != nil {
+		log.Printf("Unable to load journal: %v", err)
+	}
+	modPath := config.ModulePath()
+	if base := lastBaseEntry(journalEntries); len(base.Save) > 0 {
+		modPath = filepath.Join(config.ModulesPath, base.Save+flamedata.ModuleFileExt)
+	}
+	modData, err := importModule(modPath)
 	if err != nil {
 		panic(fmt.Errorf("Unable to load game module: %v", err))
 	}
 	game = newGame(modData)
 	burn.Module = game.Module
+	journal = newGameJournal(config.JournalFile, journalEntries...)
+	replayJournal(journalEntries)
+	burn.AddToolHandler(scriptsTool, handleScriptsCommand)
+	burn.AddToolHandler(shutdownTool, handleShutdownCommand)
+	burn.AddToolHandler(configTool, handleConfigCommand)
 	addr := fmt.Sprintf("%s:%s", config.Host, config.Port)
 	log.Printf("%s(%s)@%s", config.Name, config.Version, addr)
 	go update()
+	go handleSignals()
+	go watchConfig()
 	http.HandleFunc("/", handleHttpReq)
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	server = &http.Server{Addr: addr}
+	err = server.ListenAndServe()
+	if err != http.ErrServerClosed {
 		panic(fmt.Errorf("Unable to start server: %v", err))
 	}
+	<-shutdownDone
 }
 
 // update handles client enter/leave, requests and
 // communication between clients.
 func update() {
 	clients := make(map[string]*Client)
+	expireTicker := time.NewTicker(time.Second)
+	closed := false
 	for {
 		select {
 		case user := <-enter:
+			if close {
+				user.Close()
+				continue
+			}
 			clients[user.RemoteAddr().String()] = user
 			user.Out <- response.Response{Logon: true}
 			log.Printf("Enters: %s", user.RemoteAddr())
@@ -134,35 +209,87 @@ func update() {
 				continue
 			}
 			if client.User() != nil {
+				game.SaveUserChars(client.User())
 				game.DeactivateUserChars(client.User())
+				leaveParty(client.User())
+				notifyPresence(client.User(), false)
+				game.fireEvent(logoutEvent, client.User().ID())
 			}
 			client.Close()
 			delete(clients, addr)
 			log.Printf("Leaves: %s", addr)
 		case req := <-requests:
+			if closed {
+				continue
+			}
 			handleRequest(req)
 		case resp := <-charResponses:
 			for _, c := range clients {
-				if c.User() != nil && !c.User().Controls(resp.CharID, resp.CharSerial) {
+				if c.User() == nil || !c.User().Controls(resp.CharID, resp.CharSerial) {
 					continue
 				}
 				updateClient(c, resp.Response)
 				break
 			}
+		case resp := <-userResponses:
+			for _, c := range clients {
+				if c.User() == nil {
+					continue
+				}
+				if len(resp.UserID) > 0 && c.User().ID() != resp.UserID {
+					continue
+				}
+				updateClient(c, resp.Response)
+			}
 		case req := <-confirmRequests:
 			pendingReqs[req.ID] = req
 		case con := <-confirmed:
-			req := pendingReqs[con.ID]
-			if con.Client != nil && !con.Client.User().Controls(req.CharID, req.CharSerial) {
+			if closed {
+				continue
+			}
+			handleConfirm(con)
+		case source := <-configReload:
+			_, err := reloadConfig(source)
+			if err != nil {
+				log.Printf("Unable to reload config: %v", err)
+			}
+		case expr := <-scriptExprs:
+			res, out := burn.HandleExpression(expr.Expr)
+			expr.Result <- scriptResult{res, out}
+		case <-expireTicker.C:
+			if closed {
 				continue
 			}
-			handleConfirmedRequest(req)
-			delete(pendingReqs, int(con.ID))
+			expireMarketListings()
+			expirePartyRolls()
+			expirePartyInvites()
+			expirePendingReqs()
+			game.merchants.Restock(game.Chapter().Characters())
+			game.movement.Check(game.usersChars)
+			game.events.Check(game, game.usersChars())
+			game.autosave()
+			game.autosaveUsersChars()
 		case resp := <-load:
+			for _, c := range clients {
+				if c.User() != nil {
+					game.SaveUserChars(c.User())
+				}
+			}
+			game.Stop()
+			game.Wait()
 			flameres.Clear()
 			serial.Reset()
-			game.StopScripts()
 			game = newGame(resp.Module)
+			burn.Module = game.Module
+			for _, c := range clients {
+				if c.User() != nil {
+					game.injectUserChars(c.User())
+				}
+			}
+			err := journal.Compact(resp.Save, journal.Seq(), time.Time{})
+			if err != nil {
+				log.Printf("Unable to compact journal: %v", err)
+			}
 			for _, c := range clients {
 				if c.User() == nil {
 					continue
@@ -176,10 +303,9 @@ func update() {
 			}
 			updateClient(c, response.Response{})
 		}
-		if close {
-			// Wait some time before closing the server to ensure that
-			// all clients will receive closed response.
-			time.AfterFunc(time.Duration(2)*time.Second, closeServer)
+		if close && !closed {
+			closed = true
+			closeServer(clients)
 		}
 	}
 }
@@ -238,13 +364,18 @@ func updateClient(client *Client, resp response.Response) {
 	// Update user characters.
 	if client.User() != nil {
 		game.UpdateUserChars(client.User())
-		for _, c := range client.User().Chars() {
+		for _, c := range client.User().ActiveChars() {
 			charResp := response.Character{c.ID, c.Serial}
 			resp.Character = append(resp.Character, charResp)
 		}
 	}
 	// Send update response.
-	resp.Update = response.Update{Module: game.UserData(client.User()), Message: config.Message}
+	resp.Update = response.Update{
+		Module:  game.UserData(client.User()),
+		Paths:   game.UserPaths(client.User()),
+		Party:   partyResponse(client.User()),
+		Message: config.Message,
+	}
 	resp.Logon = client.User() == nil
 	resp.Closed = close
 	resp.Paused = game.pause
@@ -267,12 +398,3 @@ func clientWriter(c *Client) {
 		}
 	}
 }
-
-// closeServer saves current server configuration and terminates the program.
-func closeServer() {
-	err := config.Save()
-	if err != nil {
-		log.Printf("Unable to save config: %v", err)
-	}
-	os.Exit(0)
-}
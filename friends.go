@@ -0,0 +1,123 @@
+/*
+ * friends.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/response"
+	"github.com/isangeles/fire/user"
+)
+
+// handleFriendAddRequest handles friend-add request.
+func handleFriendAddRequest(cli *Client, id string) error {
+	if data.User(id) == nil {
+		return fmt.Errorf("User not found: %s", id)
+	}
+	if id == cli.User().ID() {
+		return fmt.Errorf("Unable to add self")
+	}
+	cli.User().AddFriend(id)
+	saveUser(cli.User())
+	return nil
+}
+
+// handleFriendRemoveRequest handles friend-remove request.
+func handleFriendRemoveRequest(cli *Client, id string) error {
+	if !cli.User().Friend(id) {
+		return fmt.Errorf("Not a friend: %s", id)
+	}
+	cli.User().RemoveFriend(id)
+	saveUser(cli.User())
+	return nil
+}
+
+// handleFriendListRequest handles friend-list request.
+// Friends that ignore the client user are always shown as offline.
+func handleFriendListRequest(cli *Client) (friends []response.Friend) {
+	for _, id := range cli.User().Friends() {
+		friend := data.User(id)
+		friendResp := response.Friend{
+			UserID: id,
+			Online: friend != nil && friend.Logged && !friend.Ignores(cli.User().ID()),
+		}
+		friends = append(friends, friendResp)
+	}
+	return
+}
+
+// handleIgnoreAddRequest handles ignore-add request.
+func handleIgnoreAddRequest(cli *Client, id string) error {
+	if data.User(id) == nil {
+		return fmt.Errorf("User not found: %s", id)
+	}
+	if id == cli.User().ID() {
+		return fmt.Errorf("Unable to ignore self")
+	}
+	cli.User().Ignore(id)
+	saveUser(cli.User())
+	return nil
+}
+
+// handleIgnoreRemoveRequest handles ignore-remove request.
+func handleIgnoreRemoveRequest(cli *Client, id string) error {
+	if !cli.User().Ignores(id) {
+		return fmt.Errorf("Not ignored: %s", id)
+	}
+	cli.User().Unignore(id)
+	saveUser(cli.User())
+	return nil
+}
+
+// notifyPresence sends presence response about specified user
+// to all logged users with this user on their friends lists.
+// Users ignored by specified user are not notified.
+func notifyPresence(usr *user.User, online bool) {
+	presence := response.Friend{UserID: usr.ID(), Online: online}
+	resp := response.Response{Presence: []response.Friend{presence}}
+	for _, u := range data.Users() {
+		if !u.Logged || !u.Friend(usr.ID()) || usr.Ignores(u.ID()) {
+			continue
+		}
+		userResp := userResponse{Response: resp, UserID: u.ID()}
+		sendResp := func() { userResponses <- userResp }
+		go sendResp()
+	}
+}
+
+// charOwner returns user that owns character with specified ID
+// and serial, or nil if the character is not owned by any user.
+func charOwner(id, serial string) *user.User {
+	for _, u := range data.Users() {
+		if u.Owns(id, serial) {
+			return u
+		}
+	}
+	return nil
+}
+
+// ignoredBy checks if specified user is ignored by the owner of
+// character with specified ID and serial.
+func ignoredBy(usr *user.User, id, serial string) bool {
+	owner := charOwner(id, serial)
+	return owner != nil && owner.Ignores(usr.ID())
+}
@@ -25,7 +25,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/isangeles/flame"
@@ -34,11 +38,11 @@ import (
 	flamedata "github.com/isangeles/flame/data"
 	flameres "github.com/isangeles/flame/data/res"
 	"github.com/isangeles/flame/flag"
-
-	"github.com/isangeles/burn/ash"
+	"github.com/isangeles/flame/serial"
 
 	"github.com/isangeles/fire/config"
 	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
 	"github.com/isangeles/fire/response"
 	"github.com/isangeles/fire/user"
 )
@@ -48,8 +52,22 @@ const inactiveCharFlag = flag.Flag("flagFireInactive")
 // Server-side wrapper for game.
 type Game struct {
 	*flame.Module
-	scripts map[string]*ash.Script
-	pause   bool
+	scripts       *gameScripts
+	newCharPolicy res.NewCharPolicyData
+	movement      *movementTracker
+	paths         *charPaths
+	merchants     *merchants
+	events        *serverEvents
+	schedule      *scheduler
+	areaMaps      map[string]res.AreaMapData
+	started       time.Time
+	lastSave      time.Time
+	lastCharsSave time.Time
+	restored      time.Time
+	pause         bool
+	stopped       atomic.Bool
+	updateMutex   sync.Mutex
+	done          chan bool
 }
 
 // newGame creates game for specified module data.
@@ -57,12 +75,39 @@ type Game struct {
 // specified in the config package.
 func newGame(data flameres.ModuleData) *Game {
 	g := Game{
-		Module:  flame.NewModule(data),
-		scripts: make(map[string]*ash.Script),
+		Module:   flame.NewModule(data),
+		started:  time.Now(),
+		scripts:  newGameScripts(),
+		events:   newServerEvents(),
+		movement: newMovementTracker(),
+		paths:    newCharPaths(),
+		done:     make(chan bool, 1),
 	}
 	g.AddChangeChapterEvent(g.changeChapter)
+	g.loadAreaMaps()
+	err := g.loadNewCharPolicy()
+	if err != nil {
+		log.Printf("Game: unable to load new character policy: %v",
+			err)
+	}
+	err = g.loadMerchants()
+	if err != nil {
+		log.Printf("Game: unable to load merchants: %v", err)
+	}
+	err = g.loadEventScripts()
+	if err != nil {
+		log.Printf("Game: unable to load event scripts: %v", err)
+	}
+	err = g.loadSchedule()
+	if err != nil {
+		log.Printf("Game: unable to load script schedule: %v", err)
+	}
 	go g.update()
-	err := g.runChapterScripts()
+	err = g.runModuleScripts()
+	if err != nil {
+		log.Printf("Game: unable to run module scripts: %v", err)
+	}
+	err = g.runChapterScripts()
 	if err != nil {
 		log.Printf("Game: unable to run chapter scripts: %v",
 			err)
@@ -80,37 +125,11 @@ func (g *Game) SpawnChar(char *character.Character) error {
 	area.AddObject(char)
 	char.SetPosition(g.Chapter().Conf().StartPosX, g.Chapter().Conf().StartPosY)
 	char.SetDestPoint(g.Chapter().Conf().StartPosX, g.Chapter().Conf().StartPosY)
+	g.movement.Reset(char)
+	g.paths.Clear(char)
 	return nil
 }
 
-// ValidNewCharacter checks if specified data is valid  for the
-// new character in current chapter.
-func (g *Game) ValidNewCharacter(data flameres.CharacterData) bool {
-	if data.Level > g.Chapter().Conf().StartLevel {
-		return false
-	}
-	attrs := data.Attributes.Str + data.Attributes.Con + data.Attributes.Dex +
-		data.Attributes.Int + data.Attributes.Wis
-	if attrs > g.Chapter().Conf().StartAttrs {
-		return false
-	}
-	for _, i := range data.Inventory.Items {
-		for _, id := range g.Chapter().Conf().StartItems {
-			if i.ID != id {
-				return false
-			}
-		}
-	}
-	for _, s := range data.Skills {
-		for _, id := range g.Chapter().Conf().StartSkills {
-			if s.ID != id {
-				return false
-			}
-		}
-	}
-	return true
-}
-
 // UpdateUserChars adds game characters to the specified user according to the
 // user configuration and removes characters that don't exists anymore.
 func (g *Game) UpdateUserChars(usr *user.User) {
@@ -120,7 +139,7 @@ func (g *Game) UpdateUserChars(usr *user.User) {
 	// Add new characters.
 outer:
 	for _, c := range g.Chapter().Characters() {
-		if usr.Controls(c.ID(), c.Serial()) {
+		if usr.Owns(c.ID(), c.Serial()) {
 			continue
 		}
 		for _, f := range usr.CharFlags() {
@@ -148,27 +167,142 @@ func (g *Game) AddTranslationAll(data flameres.TranslationData) {
 	}
 }
 
+// Snapshot returns data of the game module taken between
+// the game updates, so the data doesn't contain partially
+// applied update.
+func (g *Game) Snapshot() flameres.ModuleData {
+	g.updateMutex.Lock()
+	defer g.updateMutex.Unlock()
+	return g.Data()
+}
+
+// Stop stops the game update loop and all currently
+// running scripts.
+func (g *Game) Stop() {
+	g.stopped.Store(true)
+	g.StopScripts()
+}
+
+// Wait waits until the game update loop returns after
+// the game stop.
+func (g *Game) Wait() {
+	<-g.done
+}
+
 // StopScripts stops all currently running scripts.
 func (g *Game) StopScripts() {
-	for _, s := range g.scripts {
+	for _, s := range g.scripts.List() {
 		s.Stop(true)
 	}
 }
 
 // ActivateUserChars removes deactivated char flag from
-// all characters of the specified user.
+// all characters of the specified user and restores
+// user characters removed from the game world or saved
+// after the game start.
+// All user characters are set as active.
 func (g *Game) ActivateUserChars(usr *user.User) {
+	usr.SetActiveChars()
+	g.injectUserChars(usr)
 	for _, c := range g.UserChars(usr) {
 		c.RemoveFlag(inactiveCharFlag)
 	}
+	if len(usr.OfflineChars()) < 1 {
+		return
+	}
+	for _, c := range usr.OfflineChars() {
+		err := g.restoreChar(c)
+		if err != nil {
+			log.Printf("Game: unable to restore user character: %s: %s %s: %v",
+				usr.ID(), c.Char.ID, c.Char.Serial, err)
+			continue
+		}
+		usr.RemoveOfflineChar(c.Char.ID, c.Char.Serial)
+	}
+	saveUser(usr)
 }
 
-// DeactivatesUserChars add deactivated char flag to all
-// characters of the specified user.
+// DeactivatesUserChars handles all characters of the specified user
+// according to the logout policy from the config package.
+// By default, the deactivated char flag is added to all user
+// characters.
 func (g *Game) DeactivateUserChars(usr *user.User) {
-	for _, c := range g.UserChars(usr) {
-		c.AddFlag(inactiveCharFlag)
+	g.deactivateChars(usr, g.userOwnedChars(usr))
+}
+
+// SelectUserChars sets characters with specified IDs and serial values as
+// active characters of the specified user.
+// Selected characters removed from the game world are restored, and all
+// other user characters are deactivated according to the logout policy.
+func (g *Game) SelectUserChars(usr *user.User, chars ...user.Character) error {
+	offlineChars := make(map[string]res.OfflineCharData)
+	for _, c := range usr.OfflineChars() {
+		offlineChars[c.Char.ID+c.Char.Serial] = c
+	}
+	for _, c := range chars {
+		_, offline := offlineChars[c.ID+c.Serial]
+		if !usr.Owns(c.ID, c.Serial) && !offline {
+			return fmt.Errorf("not a user character: %s %s", c.ID, c.Serial)
+		}
 	}
+	// Restore selected offline characters.
+	for _, c := range chars {
+		offlineChar, ok := offlineChars[c.ID+c.Serial]
+		if !ok {
+			continue
+		}
+		err := g.restoreChar(offlineChar)
+		if err != nil {
+			return fmt.Errorf("unable to restore character: %s %s: %v",
+				c.ID, c.Serial, err)
+		}
+		usr.RemoveOfflineChar(c.ID, c.Serial)
+	}
+	// Update active characters.
+	usr.SetActiveChars(chars...)
+	g.UpdateUserChars(usr)
+	var inactiveChars []*character.Character
+	for _, c := range g.userOwnedChars(usr) {
+		if !usr.Active(c.ID(), c.Serial()) {
+			inactiveChars = append(inactiveChars, c)
+			continue
+		}
+		c.RemoveFlag(inactiveCharFlag)
+	}
+	g.deactivateChars(usr, inactiveChars)
+	saveUser(usr)
+	return nil
+}
+
+// DeleteUserChar removes character with specified ID and serial value
+// from the game world, chapter resources, and the specified user.
+func (g *Game) DeleteUserChar(usr *user.User, id, serial string) error {
+	deleted := false
+	for _, c := range usr.OfflineChars() {
+		if c.Char.ID != id || c.Char.Serial != serial {
+			continue
+		}
+		usr.RemoveOfflineChar(id, serial)
+		deleted = true
+	}
+	usr.RemoveSavedChar(id, serial)
+	if usr.Owns(id, serial) {
+		char := g.Chapter().Character(id, serial)
+		if char != nil {
+			area := g.Chapter().ObjectArea(char)
+			if area != nil {
+				area.RemoveObject(char)
+			}
+		}
+		usr.RemoveChar(user.Character{id, serial})
+		deleted = true
+	}
+	if !deleted {
+		return fmt.Errorf("not a user character: %s %s", id, serial)
+	}
+	g.removeCharData(id, serial)
+	saveUser(usr)
+	return nil
 }
 
 // UserChars returns all game characters controlled by
@@ -182,6 +316,27 @@ func (g *Game) UserChars(usr *user.User) (chars []*character.Character) {
 	return
 }
 
+// usersChars returns all game characters owned by
+// the server users.
+func (g *Game) usersChars() (chars []*character.Character) {
+	for _, u := range data.Users() {
+		chars = append(chars, g.userOwnedChars(u)...)
+	}
+	return
+}
+
+// userOwnedChars returns all game characters owned by
+// the specified user, including characters not selected
+// as active.
+func (g *Game) userOwnedChars(usr *user.User) (chars []*character.Character) {
+	for _, c := range g.Chapter().Characters() {
+		if usr.Owns(c.ID(), c.Serial()) {
+			chars = append(chars, c)
+		}
+	}
+	return
+}
+
 // NotifyNearChars sends response to all objects that can
 // see(have it in sight range) specified area object.
 func (g *Game) NotifyNearObjects(ob area.Object, resp response.Response) {
@@ -201,6 +356,19 @@ func (g *Game) NotifyNearObjects(ob area.Object, resp response.Response) {
 
 }
 
+// NotifyAreaObjects sends specified response to all objects
+// in specified area.
+func (g *Game) NotifyAreaObjects(area *area.Area, resp response.Response) {
+	for _, ob := range area.Objects() {
+		charResp := charResponse{
+			Response:   resp,
+			CharID:     ob.ID(),
+			CharSerial: ob.Serial(),
+		}
+		charResponses <- charResp
+	}
+}
+
 // UserData returns game data for server users.
 // Data like characers of incative(offline) users
 // will be excluded.
@@ -238,85 +406,325 @@ func (g *Game) UserData(usr *user.User) flameres.ModuleData {
 	return data
 }
 
+// UserPaths returns paths of all characters in sight of
+// characters controlled by specified user.
+func (g *Game) UserPaths(usr *user.User) (paths []response.Path) {
+	for _, c := range g.Chapter().Characters() {
+		points := g.paths.Path(c)
+		if len(points) < 1 {
+			continue
+		}
+		x, y := c.Position()
+		if !usr.Controls(c.ID(), c.Serial()) && !g.userSees(usr, x, y) {
+			continue
+		}
+		path := response.Path{
+			ID:     c.ID(),
+			Serial: c.Serial(),
+			Points: points,
+		}
+		paths = append(paths, path)
+	}
+	return
+}
+
 // userSees checks if specified x/y position is in sight of any
-// character controlled by the user.
+// character controlled by the user or by other members of the
+// user party.
 func (g *Game) userSees(usr *user.User, x, y float64) bool {
-	for _, c := range g.UserChars(usr) {
-		if c.InSight(x, y) {
-			return true
+	users := append([]*user.User{usr}, partyUsers(usr)...)
+	for _, u := range users {
+		for _, c := range g.UserChars(u) {
+			if c.InSight(x, y) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+// deactivateChars handles specified characters of the user according
+// to the logout policy from the config package.
+func (g *Game) deactivateChars(usr *user.User, chars []*character.Character) {
+	if config.LogoutPolicy == config.LogoutDespawn {
+		g.despawnChars(usr, chars)
+		return
+	}
+	for _, c := range chars {
+		c.AddFlag(inactiveCharFlag)
+	}
+}
+
+// despawnChars removes specified characters from the game world
+// and saves them as offline characters of the specified user.
+func (g *Game) despawnChars(usr *user.User, chars []*character.Character) {
+	if len(chars) < 1 {
+		return
+	}
+	for _, c := range chars {
+		offlineChar := res.OfflineCharData{Char: c.Data()}
+		offlineChar.PosX, offlineChar.PosY = c.Position()
+		area := g.Chapter().ObjectArea(c)
+		if area != nil {
+			offlineChar.Area = area.ID()
+			area.RemoveObject(c)
+		}
+		usr.AddOfflineChar(offlineChar)
+	}
+	saveUser(usr)
+}
+
+// removeCharData removes data of character with specified ID and
+// serial value from the module and chapter resources.
+// Data without serial value is removed only if there is no other
+// character with the same ID in the current chapter.
+func (g *Game) removeCharData(id, serial string) {
+	removable := func(data flameres.CharacterData) bool {
+		if data.ID != id {
+			return false
+		}
+		if data.Serial == serial {
+			return true
+		}
+		if len(data.Serial) > 0 {
+			return false
+		}
+		for _, c := range g.Chapter().Characters() {
+			if c.ID() == id {
+				return false
+			}
+		}
+		return true
+	}
+	var chars []flameres.CharacterData
+	for _, c := range g.Chapter().Resources().Characters {
+		if !removable(c) {
+			chars = append(chars, c)
+		}
+	}
+	g.Chapter().Resources().Characters = chars
+	chars = nil
+	for _, c := range g.Resources().Characters {
+		if !removable(c) {
+			chars = append(chars, c)
+		}
+	}
+	g.Resources().Characters = chars
+}
+
+// restoreChar places character from specified offline character
+// data in the game world.
+// The character is placed on its last position, or on the start
+// position of the current chapter if the last area does not exist
+// anymore.
+func (g *Game) restoreChar(offlineChar res.OfflineCharData) error {
+	char, ok := serial.Object(offlineChar.Char.ID, offlineChar.Char.Serial).(*character.Character)
+	if !ok {
+		char = character.New(offlineChar.Char)
+	}
+	return g.placeChar(char, offlineChar.Area, offlineChar.PosX, offlineChar.PosY)
+}
+
+// placeChar places specified character on specified position in
+// the area with specified ID, or on the start position of the current
+// chapter if there is no such area.
+func (g *Game) placeChar(char *character.Character, areaID string, x, y float64) error {
+	area := g.Chapter().Area(areaID)
+	if area == nil {
+		return g.SpawnChar(char)
+	}
+	area.AddObject(char)
+	char.SetPosition(x, y)
+	char.SetDestPoint(x, y)
+	g.movement.Reset(char)
+	g.paths.Clear(char)
+	return nil
+}
+
 // update handles game update loop.
 func (g *Game) update() {
+	defer func() { g.done <- true }()
 	update := time.Now()
-	for {
+	for !g.stopped.Load() {
 		if g.pause {
 			continue
 		}
 		// Delta.
 		delta := time.Since(update).Milliseconds()
 		// Update.
+		g.updateMutex.Lock()
 		g.Module.Update(delta)
+		g.paths.Follow()
+		g.schedule.Run(g)
+		g.updateMutex.Unlock()
 		update = time.Now()
-		time.Sleep(time.Duration(config.UpdateBreak) * time.Millisecond)
+		config.RLock()
+		updateBreak := config.UpdateBreak
+		config.RUnlock()
+		time.Sleep(time.Duration(updateBreak) * time.Millisecond)
 	}
 }
 
 // changeChapter handles chapter change triggered by specified character.
 func (g *Game) changeChapter(char *character.Character) {
-	// Change chapter.
+	err := g.setCharChapter(char)
+	if err != nil {
+		log.Printf("Unable to change chapter: %v", err)
+		return
+	}
+	// Notify client about chapter change.
+	resp := charResponse{
+		Response:   response.Response{ChangeChapter: true},
+		CharID:     char.ID(),
+		CharSerial: char.Serial(),
+	}
+	sendCharResp := func() { charResponses <- resp }
+	go sendCharResp()
+	g.fireEvent(chapterChangeEvent, scriptTarget(char.ID(), char.Serial()),
+		g.Conf().Chapter)
+}
+
+// setCharChapter changes the current chapter to the chapter of specified
+// character and respawns the character in the new chapter.
+// Clients are not notified about the change, so the chapter can be
+// changed also by the journal replay, before the server update loop
+// starts.
+func (g *Game) setCharChapter(char *character.Character) error {
+	prevChapter := g.Conf().Chapter
 	g.Conf().Chapter = char.ChapterID()
 	chapterPath := filepath.Join(g.Conf().ChaptersPath(), g.Conf().Chapter)
 	chapterData, err := flamedata.ImportChapterDir(chapterPath)
 	if err != nil {
-		log.Printf("Unable to change chapter: unable to load chapter data: %v",
+		g.Conf().Chapter = prevChapter
+		return fmt.Errorf("unable to load chapter data: %v", err)
+	}
+	err = journalChapter(char)
+	if err != nil {
+		log.Printf("Unable to change chapter: unable to journal chapter change: %v",
 			err)
-		return
 	}
+	// Stop scripts of the previous chapter.
+	g.stopScriptsIn(path.Join("chapters", prevChapter))
+	g.events.ResetAreas()
 	chapter := flame.NewChapter(g.Module, chapterData)
 	g.SetChapter(chapter)
+	g.loadAreaMaps()
+	err = g.runChapterScripts()
+	if err != nil {
+		log.Printf("Unable to change chapter: unable to run chapter scripts: %v",
+			err)
+	}
 	// Respawn character.
 	err = g.SpawnChar(char)
 	if err != nil {
 		log.Printf("Unable to change chapter: unable to respawn character: %v",
 			err)
 	}
-	// Notify client about chapter change.
-	resp := charResponse{
-		Response:   response.Response{ChangeChapter: true},
-		CharID:     char.ID(),
-		CharSerial: char.Serial(),
+	return nil
+}
+
+// loadNewCharPolicy loads new character policy from
+// the module server directory.
+func (g *Game) loadNewCharPolicy() error {
+	path := filepath.Join(g.Conf().Path, config.ModuleServerPath, newCharPolicyFile)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	policy, err := data.ImportNewCharPolicy(path)
+	if err != nil {
+		return fmt.Errorf("unable to import policy: %v", err)
 	}
-	charResponses <- resp
+	g.newCharPolicy = policy
+	return nil
+}
+
+// runModuleScripts starts all module-wide ash scripts.
+// Module scripts are not stopped on chapter change.
+func (g *Game) runModuleScripts() error {
+	return g.runScripts(g.moduleScriptsPath())
 }
 
 // runChapterScripts starts all ash scripts for
 // current chapter.
 func (g *Game) runChapterScripts() error {
-	path := filepath.Join(g.Conf().Path, config.ModuleServerPath, "chapters",
+	return g.runScripts(g.chapterScriptsPath())
+}
+
+// runAreaScripts starts all ash scripts for area with specified
+// ID in current chapter.
+func (g *Game) runAreaScripts(areaID string) error {
+	return g.runScripts(g.areaScriptsPath(areaID))
+}
+
+// moduleScriptsPath returns path to the directory with
+// module-wide ash scripts.
+func (g *Game) moduleScriptsPath() string {
+	return filepath.Join(g.Conf().Path, config.ModuleServerPath, "scripts")
+}
+
+// chapterScriptsPath returns path to the directory with
+// ash scripts for current chapter.
+func (g *Game) chapterScriptsPath() string {
+	return filepath.Join(g.Conf().Path, config.ModuleServerPath, "chapters",
 		g.Chapter().Conf().ID, "scripts")
+}
+
+// areaScriptsPath returns path to the directory with ash
+// scripts for area with specified ID in current chapter.
+func (g *Game) areaScriptsPath(areaID string) string {
+	return filepath.Join(g.Conf().Path, config.ModuleServerPath, "chapters",
+		g.Chapter().Conf().ID, "areas", areaID, "scripts")
+}
+
+// stopAreaScripts stops all running ash scripts for area with
+// specified ID in current chapter.
+func (g *Game) stopAreaScripts(areaID string) {
+	dir := path.Join("chapters", g.Chapter().Conf().ID, "areas", areaID)
+	g.stopScriptsIn(dir)
+}
+
+// runScripts starts all ash scripts from directory with
+// specified path.
+func (g *Game) runScripts(path string) error {
+	_, err := g.startScripts(path, false)
+	return err
+}
+
+// startScripts starts ash scripts from directory with specified
+// path. If only new is true, scripts from files that were already
+// started before are skipped.
+// Returns names of started scripts.
+func (g *Game) startScripts(path string, onlyNew bool) ([]string, error) {
 	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
-		return nil
+		return nil, nil
 	}
-	scripts, err := data.ImportScripts(path)
+	scripts, err := data.ImportScriptSources(path)
 	if err != nil {
-		return fmt.Errorf("unable to import scripts: %v", err)
+		return nil, fmt.Errorf("unable to import scripts: %v", err)
 	}
-	for _, s := range scripts {
+	var started []string
+	for p, text := range scripts {
+		if onlyNew && g.scripts.Started(p) {
+			continue
+		}
+		s, err := newGameScript(g.scriptName(p), p, text, time.Now())
+		if err != nil {
+			log.Printf("Game: unable to create script: %s: %v", p, err)
+			continue
+		}
+		g.scripts.SetStarted(p)
 		go g.runScript(s)
+		started = append(started, s.Name())
 	}
-	return nil
+	return started, nil
 }
 
-// runScript runs specified ash script.
-func (g *Game) runScript(script *ash.Script) {
-	g.scripts[script.Name()] = script
-	err := ash.Run(script)
-	if err != nil {
-		log.Printf("Game: unable to run ash script: %v", err)
+// stopScriptsIn stops all running ash scripts from specified
+// directory, relative to the module server directory.
+func (g *Game) stopScriptsIn(dir string) {
+	for _, s := range g.scripts.List() {
+		if strings.HasPrefix(s.Name(), dir+"/") {
+			s.Stop(true)
+		}
 	}
-	delete(g.scripts, script.Name())
 }
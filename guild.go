@@ -0,0 +1,517 @@
+/*
+ * guild.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/isangeles/flame/character"
+	"github.com/isangeles/flame/item"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+)
+
+var (
+	guilds           = make(map[string]*guild)
+	guildNamePattern = regexp.MustCompile(`^[A-Za-z0-9 ]{3,32}$`)
+)
+
+// Struct for guild.
+// Guild ranks are ordered from the highest one,
+// members with the highest rank are guild leaders.
+type guild struct {
+	res.GuildData
+	invites map[string]bool
+}
+
+// newGuild creates new guild from specified data.
+func newGuild(data res.GuildData) *guild {
+	g := guild{
+		GuildData: data,
+		invites:   make(map[string]bool),
+	}
+	return &g
+}
+
+// defaultGuildRanks returns ranks for a newly created guild.
+func defaultGuildRanks() []res.GuildRankData {
+	return []res.GuildRankData{
+		{ID: "leader", Invite: true, Kick: true, Promote: true, Motd: true, BankLimit: -1},
+		{ID: "officer", Invite: true, Kick: true, Motd: true, BankLimit: 20},
+		{ID: "member", BankLimit: 5},
+	}
+}
+
+// loadGuilds loads all guilds from the server data directory.
+func loadGuilds() error {
+	guildsData, err := data.LoadGuilds(config.GuildsPath)
+	if err != nil {
+		return err
+	}
+	guilds = make(map[string]*guild)
+	for _, gd := range guildsData {
+		guilds[gd.ID] = newGuild(gd)
+	}
+	return nil
+}
+
+// userGuild returns guild of the user with specified ID, or nil
+// if the user is not a guild member.
+func userGuild(id string) *guild {
+	for _, g := range guilds {
+		if g.Member(id) != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+// Member returns member data for the user with specified ID, or nil
+// if the user is not a guild member.
+func (g *guild) Member(id string) *res.GuildMemberData {
+	for i := range g.Members {
+		if g.Members[i].UserID == id {
+			return &g.Members[i]
+		}
+	}
+	return nil
+}
+
+// Rank returns rank of the guild member with specified ID and
+// the position of the rank, or nil and -1 if there is no such member.
+func (g *guild) Rank(id string) (*res.GuildRankData, int) {
+	member := g.Member(id)
+	if member == nil {
+		return nil, -1
+	}
+	for i := range g.Ranks {
+		if g.Ranks[i].ID == member.Rank {
+			return &g.Ranks[i], i
+		}
+	}
+	return nil, -1
+}
+
+// rankPos returns position of the rank with specified ID, or -1 if
+// there is no such rank.
+func (g *guild) rankPos(id string) int {
+	for i, r := range g.Ranks {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove removes user with specified ID from the guild members.
+// The guild is removed if there are no members left.
+func (g *guild) Remove(id string) {
+	for i, m := range g.Members {
+		if m.UserID == id {
+			g.Members = append(g.Members[:i], g.Members[i+1:]...)
+			break
+		}
+	}
+	if len(g.Members) < 1 {
+		delete(guilds, g.ID)
+		err := data.RemoveGuild(config.GuildsPath, g.ID)
+		if err != nil {
+			log.Printf("Guild: %s: unable to remove guild: %v", g.ID, err)
+		}
+		return
+	}
+	g.ensureLeader()
+	g.Save()
+}
+
+// ensureLeader promotes the highest ranked member to the guild
+// leader if there is no leader in the guild.
+func (g *guild) ensureLeader() {
+	if len(g.Members) < 1 {
+		return
+	}
+	next := &g.Members[0]
+	for i := range g.Members {
+		pos := g.rankPos(g.Members[i].Rank)
+		if pos == 0 {
+			return
+		}
+		if pos < g.rankPos(next.Rank) {
+			next = &g.Members[i]
+		}
+	}
+	next.Rank = g.Ranks[0].ID
+}
+
+// Save saves guild in the server data directory.
+func (g *guild) Save() {
+	err := data.SaveGuild(config.GuildsPath, g.GuildData)
+	if err != nil {
+		log.Printf("Guild: %s: unable to save guild: %v", g.ID, err)
+	}
+}
+
+// Notify sends specified response to all guild members.
+// Member IDs are copied before the response is sent in
+// the background, so the guild can be modified right after
+// the call.
+func (g *guild) Notify(resp response.Response) {
+	members := make([]string, 0, len(g.Members))
+	for _, m := range g.Members {
+		members = append(members, m.UserID)
+	}
+	sendResp := func() {
+		for _, m := range members {
+			userResponses <- userResponse{Response: resp, UserID: m}
+		}
+	}
+	go sendResp()
+}
+
+// Response returns guild response for the guild.
+func (g *guild) Response() response.Guild {
+	resp := response.Guild{
+		ID:   g.ID,
+		Motd: g.Motd,
+		Bank: make(map[string][]string),
+	}
+	for _, r := range g.Ranks {
+		resp.Ranks = append(resp.Ranks, response.GuildRank(r))
+	}
+	for _, m := range g.Members {
+		usr := data.User(m.UserID)
+		member := response.GuildMember{
+			UserID: m.UserID,
+			Rank:   m.Rank,
+			Joined: m.Joined,
+			Online: usr != nil && usr.Logged,
+		}
+		resp.Members = append(resp.Members, member)
+	}
+	for _, it := range g.Bank {
+		resp.Bank[it.ID] = append(resp.Bank[it.ID], it.Serial)
+	}
+	return resp
+}
+
+// bankItem returns position of item with specified ID and serial
+// in the guild bank, or -1 if there is no such item in the bank.
+func (g *guild) bankItem(id, serial string) int {
+	for i, it := range g.Bank {
+		if it.ID == id && it.Serial == serial {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleGuildCreateRequest handles guild-create request.
+func handleGuildCreateRequest(cli *Client, name string) error {
+	if !guildNamePattern.MatchString(name) {
+		return fmt.Errorf("Invalid guild name: %s", name)
+	}
+	if userGuild(cli.User().ID()) != nil {
+		return fmt.Errorf("Already in guild")
+	}
+	for id := range guilds {
+		if strings.EqualFold(id, name) {
+			return fmt.Errorf("Guild name already taken: %s", name)
+		}
+	}
+	data := res.GuildData{
+		ID:    name,
+		Ranks: defaultGuildRanks(),
+	}
+	leader := res.GuildMemberData{
+		UserID: cli.User().ID(),
+		Rank:   data.Ranks[0].ID,
+		Joined: time.Now().UnixMilli(),
+	}
+	data.Members = append(data.Members, leader)
+	g := newGuild(data)
+	guilds[g.ID] = g
+	g.Save()
+	return nil
+}
+
+// handleGuildInviteRequest handles guild-invite request.
+func handleGuildInviteRequest(cli *Client, id string) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	rank, _ := g.Rank(cli.User().ID())
+	if rank == nil || !rank.Invite {
+		return fmt.Errorf("No permission to invite")
+	}
+	if data.User(id) == nil {
+		return fmt.Errorf("User not found: %s", id)
+	}
+	if userGuild(id) != nil {
+		return fmt.Errorf("User is already in guild: %s", id)
+	}
+	g.invites[id] = true
+	invite := response.GuildInvite{ID: g.ID, From: cli.User().ID()}
+	resp := response.Response{GuildInvite: []response.GuildInvite{invite}}
+	sendResp := func() { userResponses <- userResponse{Response: resp, UserID: id} }
+	go sendResp()
+	return nil
+}
+
+// handleGuildAcceptRequest handles guild-accept request.
+func handleGuildAcceptRequest(cli *Client, id string) error {
+	g := guilds[id]
+	if g == nil || !g.invites[cli.User().ID()] {
+		return fmt.Errorf("Invite not found: %s", id)
+	}
+	if userGuild(cli.User().ID()) != nil {
+		return fmt.Errorf("Already in guild")
+	}
+	delete(g.invites, cli.User().ID())
+	member := res.GuildMemberData{
+		UserID: cli.User().ID(),
+		Rank:   g.Ranks[len(g.Ranks)-1].ID,
+		Joined: time.Now().UnixMilli(),
+	}
+	g.Members = append(g.Members, member)
+	g.Save()
+	return nil
+}
+
+// handleGuildLeaveRequest handles guild-leave request.
+// The last guild member can't leave the guild with items in the
+// guild bank.
+func handleGuildLeaveRequest(cli *Client) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	if len(g.Members) < 2 && len(g.Bank) > 0 {
+		return fmt.Errorf("Guild bank is not empty")
+	}
+	g.Remove(cli.User().ID())
+	return nil
+}
+
+// handleGuildKickRequest handles guild-kick request.
+// Only members with lower ranks can be kicked.
+func handleGuildKickRequest(cli *Client, id string) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	rank, pos := g.Rank(cli.User().ID())
+	if rank == nil || !rank.Kick {
+		return fmt.Errorf("No permission to kick")
+	}
+	if g.invites[id] {
+		delete(g.invites, id)
+		return nil
+	}
+	_, targetPos := g.Rank(id)
+	if targetPos < 0 {
+		return fmt.Errorf("Not a guild member: %s", id)
+	}
+	if targetPos <= pos {
+		return fmt.Errorf("Unable to kick member with equal or higher rank: %s", id)
+	}
+	g.Remove(id)
+	return nil
+}
+
+// handleGuildRankRequest handles guild-rank request.
+// Guild leaders can set any rank, other members can only set ranks
+// lower than their own for members with lower ranks.
+func handleGuildRankRequest(cli *Client, req request.GuildRank) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	rank, pos := g.Rank(cli.User().ID())
+	if rank == nil || !rank.Promote {
+		return fmt.Errorf("No permission to promote")
+	}
+	member := g.Member(req.UserID)
+	if member == nil {
+		return fmt.Errorf("Not a guild member: %s", req.UserID)
+	}
+	newPos := g.rankPos(req.Rank)
+	if newPos < 0 {
+		return fmt.Errorf("Rank not found: %s", req.Rank)
+	}
+	if pos > 0 && (g.rankPos(member.Rank) <= pos || newPos <= pos) {
+		return fmt.Errorf("Unable to set rank")
+	}
+	member.Rank = req.Rank
+	g.ensureLeader()
+	g.Save()
+	return nil
+}
+
+// handleGuildMotdRequest handles guild-motd request.
+func handleGuildMotdRequest(cli *Client, motd string) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	rank, _ := g.Rank(cli.User().ID())
+	if rank == nil || !rank.Motd {
+		return fmt.Errorf("No permission to set message of the day")
+	}
+	g.Motd = motd
+	g.Save()
+	return nil
+}
+
+// handleGuildDepositRequest handles guild-deposit request.
+func handleGuildDepositRequest(cli *Client, req request.GuildBank) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	char, ok := game.Object(req.ObjectID, req.ObjectSerial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Character not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	var items []item.Item
+	for id, serials := range req.Items {
+		for _, serial := range serials {
+			it := char.Inventory().Item(id, serial)
+			if it == nil {
+				return fmt.Errorf("Item not found: %s %s", id, serial)
+			}
+			items = append(items, it)
+		}
+	}
+	err := removeItems(char, req.Items)
+	if err != nil {
+		return fmt.Errorf("Unable to remove items: %v", err)
+	}
+	for _, it := range items {
+		escrow.Hold(it)
+		g.Bank = append(g.Bank, res.GuildItemData{ID: it.ID(), Serial: it.Serial()})
+	}
+	g.Save()
+	return nil
+}
+
+// handleGuildWithdrawRequest handles guild-withdraw request.
+// The number of items withdrawn by a member each day is limited
+// by the member rank.
+func handleGuildWithdrawRequest(cli *Client, req request.GuildBank) error {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return fmt.Errorf("Not in guild")
+	}
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	char, ok := game.Object(req.ObjectID, req.ObjectSerial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Character not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	// Check limit.
+	member := g.Member(cli.User().ID())
+	rank, _ := g.Rank(cli.User().ID())
+	if rank == nil {
+		return fmt.Errorf("Invalid member rank: %s", member.Rank)
+	}
+	today := time.Now().Unix() / 86400
+	if member.WithdrawDay != today {
+		member.WithdrawDay = today
+		member.Withdrawn = 0
+	}
+	count := 0
+	for _, serials := range req.Items {
+		count += len(serials)
+	}
+	if rank.BankLimit > -1 && member.Withdrawn+count > rank.BankLimit {
+		return fmt.Errorf("Withdraw limit exceeded: %d", rank.BankLimit)
+	}
+	// Withdraw items.
+	tx := newItemsTransaction()
+	for id, serials := range req.Items {
+		for _, serial := range serials {
+			it, err := withdrawItem(g, char, id, serial)
+			if err != nil {
+				return err
+			}
+			err = tx.Insert(char, it)
+			if err != nil {
+				return fmt.Errorf("Invalid item: %v", err)
+			}
+		}
+	}
+	err := journalCommit(transferEntry, tx)
+	if err != nil {
+		return fmt.Errorf("Unable to add items: %v", err)
+	}
+	for id, serials := range req.Items {
+		for _, serial := range serials {
+			i := g.bankItem(id, serial)
+			g.Bank = append(g.Bank[:i], g.Bank[i+1:]...)
+			escrow.Release(id, serial)
+		}
+	}
+	member.Withdrawn += count
+	g.Save()
+	return nil
+}
+
+// withdrawItem returns item with specified ID and serial from the guild
+// bank, to be added to the inventory of specified character.
+func withdrawItem(g *guild, char *character.Character, id, serial string) (item.Item, error) {
+	if g.bankItem(id, serial) < 0 {
+		return nil, fmt.Errorf("Item not found in the guild bank: %s %s", id, serial)
+	}
+	it, err := escrow.Item(id, serial)
+	if err != nil {
+		return nil, err
+	}
+	if char.Inventory().Item(id, serial) != nil {
+		return nil, fmt.Errorf("Item already withdrawn: %s %s", id, serial)
+	}
+	return it, nil
+}
+
+// handleGuildInfoRequest handles guild-info request.
+func handleGuildInfoRequest(cli *Client) (response.Guild, error) {
+	g := userGuild(cli.User().ID())
+	if g == nil {
+		return response.Guild{}, fmt.Errorf("Not in guild")
+	}
+	return g.Response(), nil
+}
@@ -0,0 +1,367 @@
+/*
+ * journal.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/isangeles/flame/character"
+	flameres "github.com/isangeles/flame/data/res"
+	"github.com/isangeles/flame/item"
+
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/user"
+)
+
+// Types of journal entries.
+const (
+	baseEntry     = "base"
+	newCharEntry  = "new-char"
+	transferEntry = "transfer-items"
+	tradeEntry    = "trade"
+	chapterEntry  = "chapter"
+	abortEntry    = "abort"
+)
+
+// Struct for write-ahead journal of game state changes.
+// Each journal starts with the base entry with the name
+// of the save that contains all changes from the entries
+// with sequence numbers lower or equal to the sequence
+// number of the base entry.
+type gameJournal struct {
+	mutex     sync.Mutex
+	path      string
+	seq       int64
+	replaying bool
+}
+
+var journal = newGameJournal("")
+
+// newGameJournal creates new journal with specified path
+// and entries.
+// Empty path means that the journal entries are not saved.
+func newGameJournal(path string, entries ...res.JournalEntryData) *gameJournal {
+	j := gameJournal{path: path}
+	for _, e := range entries {
+		if e.Seq > j.seq {
+			j.seq = e.Seq
+		}
+	}
+	return &j
+}
+
+// Seq returns sequence number of the last journal entry.
+func (j *gameJournal) Seq() int64 {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.seq
+}
+
+// Append appends specified entry to the journal and returns
+// sequence number of the entry.
+// Entries are ignored while the journal is replayed, in that
+// case the returned sequence number is 0.
+// Entries should be appended before the change is applied
+// to the game.
+func (j *gameJournal) Append(entry res.JournalEntryData) (int64, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if len(j.path) < 1 || j.replaying {
+		return 0, nil
+	}
+	entry.Seq = j.seq + 1
+	entry.Time = time.Now().UnixMilli()
+	err := data.AppendJournal(j.path, entry)
+	if err != nil {
+		return 0, fmt.Errorf("unable to append entry: %v", err)
+	}
+	j.seq = entry.Seq
+	return entry.Seq, nil
+}
+
+// Compact sets save with specified name as the journal base and
+// removes all entries with sequence numbers lower or equal to
+// specified number, as all these changes are included in the save.
+// Specified time should be the time when the save data was taken
+// from the game, zero time means that the save time is unknown.
+func (j *gameJournal) Compact(save string, seq int64, saveTime time.Time) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if len(j.path) < 1 {
+		return nil
+	}
+	entries, err := data.LoadJournal(j.path)
+	if err != nil {
+		return fmt.Errorf("unable to load journal: %v", err)
+	}
+	base := res.JournalEntryData{
+		Seq:  seq,
+		Type: baseEntry,
+		Time: time.Now().UnixMilli(),
+		Save: save,
+	}
+	if !saveTime.IsZero() {
+		base.SaveTime = saveTime.UnixMilli()
+	}
+	kept := []res.JournalEntryData{base}
+	for _, e := range entries {
+		if e.Type != baseEntry && e.Seq > seq {
+			kept = append(kept, e)
+		}
+	}
+	return data.SaveJournal(j.path, kept)
+}
+
+// SetReplaying toggles journal replay mode.
+func (j *gameJournal) SetReplaying(replaying bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.replaying = replaying
+}
+
+// lastBaseEntry returns the last base entry from specified entries.
+func lastBaseEntry(entries []res.JournalEntryData) (base res.JournalEntryData) {
+	for _, e := range entries {
+		if e.Type == baseEntry {
+			base = e
+		}
+	}
+	return
+}
+
+// replayJournal applies all changes from specified journal entries,
+// recorded after the journal base, to the game.
+func replayJournal(entries []res.JournalEntryData) {
+	journal.SetReplaying(true)
+	defer journal.SetReplaying(false)
+	game.pause = true
+	defer func() { game.pause = false }()
+	base := lastBaseEntry(entries)
+	if base.SaveTime > 0 {
+		game.restored = time.UnixMilli(base.SaveTime)
+	}
+	aborted := make(map[int64]bool)
+	for _, e := range entries {
+		if e.Type == abortEntry {
+			aborted[e.Aborted] = true
+		}
+	}
+	replayed := 0
+	for _, e := range entries {
+		if e.Type == baseEntry || e.Type == abortEntry || e.Seq <= base.Seq ||
+			aborted[e.Seq] {
+			continue
+		}
+		err := replayJournalEntry(e)
+		if err != nil {
+			log.Printf("Journal: unable to replay entry: %d: %v", e.Seq, err)
+			continue
+		}
+		if t := time.UnixMilli(e.Time); t.After(game.restored) {
+			game.restored = t
+		}
+		replayed++
+	}
+	log.Printf("Journal: replayed entries: %d", replayed)
+}
+
+// replayJournalEntry applies change from specified journal
+// entry to the game.
+func replayJournalEntry(entry res.JournalEntryData) error {
+	switch entry.Type {
+	case newCharEntry:
+		if entry.Char == nil {
+			return fmt.Errorf("no character data")
+		}
+		char := character.New(*entry.Char)
+		game.Chapter().Resources().Characters = append(game.Chapter().Resources().Characters,
+			*entry.Char)
+		err := game.SpawnChar(char)
+		if err != nil {
+			return fmt.Errorf("unable to spawn char: %v", err)
+		}
+		game.AddTranslationAll(flameres.TranslationData{entry.Char.ID, []string{entry.Name}})
+		if usr := data.User(entry.UserID); usr != nil {
+			usr.AddChar(char)
+		}
+	case transferEntry, tradeEntry:
+		tx := newItemsTransaction()
+		for _, t := range entry.Transfers {
+			err := replayTransfer(tx, t)
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Commit()
+		if err != nil {
+			return err
+		}
+		for _, t := range entry.Transfers {
+			if len(t.FromID) > 0 {
+				continue
+			}
+			for id, serials := range t.Items {
+				for _, s := range serials {
+					escrow.Release(id, s)
+				}
+			}
+		}
+	case chapterEntry:
+		char, ok := game.Object(entry.CharID, entry.Serial).(*character.Character)
+		if !ok {
+			return fmt.Errorf("character not found: %s %s", entry.CharID,
+				entry.Serial)
+		}
+		char.SetChapterID(entry.Chapter)
+		err := game.setCharChapter(char)
+		if err != nil {
+			return fmt.Errorf("unable to change chapter: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown entry type: %s", entry.Type)
+	}
+	return nil
+}
+
+// replayTransfer adds items transfer from specified journal data
+// to specified transaction.
+// Transfers without the source container are replayed as items
+// inserted from the escrow, and transfers without the target
+// container as items removed from the game world.
+func replayTransfer(tx *itemsTransaction, t res.JournalTransferData) error {
+	var to item.Container
+	if len(t.ToID) > 0 {
+		ob, ok := game.Object(t.ToID, t.ToSerial).(item.Container)
+		if !ok {
+			return fmt.Errorf("container not found: %s %s", t.ToID,
+				t.ToSerial)
+		}
+		to = ob
+	}
+	if len(t.FromID) < 1 {
+		if to == nil {
+			return fmt.Errorf("no containers")
+		}
+		for id, serials := range t.Items {
+			for _, s := range serials {
+				it, err := escrow.Item(id, s)
+				if err != nil {
+					return fmt.Errorf("invalid items: %v", err)
+				}
+				err = tx.Insert(to, it)
+				if err != nil {
+					return fmt.Errorf("invalid items: %v", err)
+				}
+			}
+		}
+		return nil
+	}
+	from, ok := game.Object(t.FromID, t.FromSerial).(item.Container)
+	if !ok {
+		return fmt.Errorf("container not found: %s %s", t.FromID,
+			t.FromSerial)
+	}
+	err := tx.Add(from, to, t.Items)
+	if err != nil {
+		return fmt.Errorf("invalid items: %v", err)
+	}
+	return nil
+}
+
+// journalNewChar appends entry about specified character created
+// by specified user to the journal and returns sequence number
+// of the entry.
+func journalNewChar(usr *user.User, name string, char *character.Character) (int64, error) {
+	charData := char.Data()
+	entry := res.JournalEntryData{
+		Type:   newCharEntry,
+		UserID: usr.ID(),
+		Name:   name,
+		Char:   &charData,
+	}
+	return journal.Append(entry)
+}
+
+// journalCommit appends entry of specified type with all transfers
+// from specified transaction to the journal and commits the
+// transaction.
+// The transaction is not committed if the entry can't be appended,
+// and the entry is aborted if the transaction fails.
+func journalCommit(entryType string, tx *itemsTransaction) error {
+	entry := res.JournalEntryData{
+		Type:      entryType,
+		Transfers: tx.Transfers(),
+	}
+	seq, err := journal.Append(entry)
+	if err != nil {
+		return fmt.Errorf("Unable to journal transfer: %v", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		journalAbort(seq)
+		return err
+	}
+	return nil
+}
+
+// journalChapter appends entry about chapter change by specified
+// character to the journal.
+func journalChapter(char *character.Character) error {
+	entry := res.JournalEntryData{
+		Type:    chapterEntry,
+		CharID:  char.ID(),
+		Serial:  char.Serial(),
+		Chapter: char.ChapterID(),
+	}
+	_, err := journal.Append(entry)
+	return err
+}
+
+// journalAbort appends entry that aborts the entry with
+// specified sequence number to the journal.
+// Aborted entries are skipped during the journal replay.
+func journalAbort(seq int64) {
+	if seq < 1 {
+		return
+	}
+	_, err := journal.Append(res.JournalEntryData{Type: abortEntry, Aborted: seq})
+	if err != nil {
+		log.Printf("Journal: unable to abort entry: %d: %v", seq, err)
+	}
+}
+
+// transferData creates journal data for specified items
+// transfer between specified containers.
+// Nil containers are represented by empty IDs and serials.
+func transferData(from, to item.Container, items map[string][]string) res.JournalTransferData {
+	transfer := res.JournalTransferData{Items: items}
+	if from != nil {
+		transfer.FromID, transfer.FromSerial = from.ID(), from.Serial()
+	}
+	if to != nil {
+		transfer.ToID, transfer.ToSerial = to.ID(), to.Serial()
+	}
+	return transfer
+}
@@ -0,0 +1,215 @@
+/*
+ * mail.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isangeles/flame/character"
+	"github.com/isangeles/flame/item"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+)
+
+// handleMailSendRequest handles mail-send request.
+// Items attached to the mail are removed from the inventory
+// of the sender character and held in the server escrow until
+// the recipient takes them.
+func handleMailSendRequest(cli *Client, req request.MailSend) error {
+	recipient := data.User(req.To)
+	if recipient == nil || recipient.Ignores(cli.User().ID()) {
+		return fmt.Errorf("Recipient not found: %s", req.To)
+	}
+	if config.MailMaxLen > 0 && len([]rune(req.Subject)) > config.MailMaxLen {
+		return fmt.Errorf("Subject too long: %d > %d", len([]rune(req.Subject)),
+			config.MailMaxLen)
+	}
+	if config.MailMaxLen > 0 && len([]rune(req.Text)) > config.MailMaxLen {
+		return fmt.Errorf("Text too long: %d > %d", len([]rune(req.Text)),
+			config.MailMaxLen)
+	}
+	if config.MailboxSize > 0 && len(recipient.Mail()) >= config.MailboxSize {
+		return fmt.Errorf("Recipient mailbox is full: %s", req.To)
+	}
+	mail := res.MailData{
+		From:    cli.User().ID(),
+		Subject: req.Subject,
+		Text:    req.Text,
+		Items:   req.Items,
+		Time:    time.Now().UnixMilli(),
+	}
+	if len(req.Items) > 0 {
+		err := attachItems(cli, req)
+		if err != nil {
+			return fmt.Errorf("Unable to attach items: %v", err)
+		}
+	}
+	recipient.AddMail(mail)
+	saveUser(recipient)
+	return nil
+}
+
+// attachItems moves items from the mail-send request to the
+// server escrow.
+func attachItems(cli *Client, req request.MailSend) error {
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	sender, ok := game.Object(req.ObjectID, req.ObjectSerial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Sender not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	var items []item.Item
+	for id, serials := range req.Items {
+		for _, serial := range serials {
+			it := sender.Inventory().Item(id, serial)
+			if it == nil {
+				return fmt.Errorf("Item not found: %s %s", id, serial)
+			}
+			items = append(items, it)
+		}
+	}
+	err := removeItems(sender, req.Items)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		escrow.Hold(it)
+	}
+	return nil
+}
+
+// handleMailListRequest handles mail-list request.
+// Returns all mail from the client user mailbox, without
+// the mail text.
+func handleMailListRequest(cli *Client) (mail []response.Mail) {
+	for _, m := range cli.User().Mail() {
+		m.Text = ""
+		mail = append(mail, mailResponse(m))
+	}
+	return
+}
+
+// handleMailReadRequest handles mail-read request.
+func handleMailReadRequest(cli *Client, id int) (response.Mail, error) {
+	mail := cli.User().MailByID(id)
+	if mail == nil {
+		return response.Mail{}, fmt.Errorf("Mail not found: %d", id)
+	}
+	if !mail.Read {
+		mail.Read = true
+		saveUser(cli.User())
+	}
+	return mailResponse(*mail), nil
+}
+
+// handleMailTakeRequest handles mail-take request.
+// All items attached to the mail are moved to the inventory
+// of specified character.
+func handleMailTakeRequest(cli *Client, req request.MailTake) error {
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	char, ok := game.Object(req.ObjectID, req.ObjectSerial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Character not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	mail := cli.User().MailByID(req.ID)
+	if mail == nil {
+		return fmt.Errorf("Mail not found: %d", req.ID)
+	}
+	left := make(map[string][]string)
+	var errs []string
+	for id, serials := range mail.Items {
+		for _, serial := range serials {
+			err := takeItem(char, id, serial)
+			if err != nil {
+				left[id] = append(left[id], serial)
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	mail.Items = left
+	saveUser(cli.User())
+	if len(errs) > 0 {
+		return fmt.Errorf("Unable to take all items: %s",
+			strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// takeItem moves item with specified ID and serial from the escrow
+// to the inventory of specified character.
+func takeItem(char *character.Character, id, serial string) error {
+	it, err := escrow.Item(id, serial)
+	if err != nil {
+		return err
+	}
+	tx := newItemsTransaction()
+	err = tx.Insert(char, it)
+	if err != nil {
+		return fmt.Errorf("Invalid item: %s %s: %v", id, serial, err)
+	}
+	err = journalCommit(transferEntry, tx)
+	if err != nil {
+		return fmt.Errorf("Unable to add item: %s %s: %v", id, serial, err)
+	}
+	escrow.Release(id, serial)
+	return nil
+}
+
+// handleMailDeleteRequest handles mail-delete request.
+// Mail with attached items can't be deleted.
+func handleMailDeleteRequest(cli *Client, id int) error {
+	mail := cli.User().MailByID(id)
+	if mail == nil {
+		return fmt.Errorf("Mail not found: %d", id)
+	}
+	if len(mail.Items) > 0 {
+		return fmt.Errorf("Mail has attached items: %d", id)
+	}
+	cli.User().RemoveMail(id)
+	saveUser(cli.User())
+	return nil
+}
+
+// mailResponse creates mail response from specified mail data.
+func mailResponse(data res.MailData) response.Mail {
+	return response.Mail{
+		ID:      data.ID,
+		From:    data.From,
+		Subject: data.Subject,
+		Text:    data.Text,
+		Items:   data.Items,
+		Time:    data.Time,
+		Read:    data.Read,
+	}
+}
@@ -0,0 +1,376 @@
+/*
+ * market.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/isangeles/flame/character"
+	flameres "github.com/isangeles/flame/data/res"
+	"github.com/isangeles/flame/item"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+)
+
+var marketplace = newMarket(res.MarketData{})
+
+// Struct for player-to-player marketplace.
+// Items listed on the marketplace are held in the server escrow
+// until they are bought, canceled or expired.
+type market struct {
+	data res.MarketData
+}
+
+// newMarket creates new marketplace from specified data.
+func newMarket(data res.MarketData) *market {
+	m := market{data: data}
+	return &m
+}
+
+// Listings returns all listings matching specified search
+// request.
+func (m *market) Listings(req request.MarketSearch) (listings []response.MarketListing) {
+	for _, l := range m.data.Listings {
+		if !strings.Contains(l.ItemID, req.ItemID) {
+			continue
+		}
+		if req.MaxPrice > 0 && l.Price > req.MaxPrice {
+			continue
+		}
+		listing := response.MarketListing{
+			ID:         l.ID,
+			SellerID:   l.SellerID,
+			ItemID:     l.ItemID,
+			ItemSerial: l.ItemSerial,
+			Price:      l.Price,
+			Expires:    l.Expires,
+		}
+		listings = append(listings, listing)
+	}
+	return
+}
+
+// Listing returns listing with specified ID or nil if there
+// is no such listing.
+func (m *market) Listing(id int) *res.MarketListingData {
+	for i := range m.data.Listings {
+		if m.data.Listings[i].ID == id {
+			return &m.data.Listings[i]
+		}
+	}
+	return nil
+}
+
+// Add adds new listing for specified item to the marketplace.
+// The item is moved to the server escrow.
+func (m *market) Add(userID string, seller *character.Character, it item.Item, price int) {
+	m.data.LastID++
+	listing := res.MarketListingData{
+		ID:           m.data.LastID,
+		UserID:       userID,
+		SellerID:     seller.ID(),
+		SellerSerial: seller.Serial(),
+		ItemID:       it.ID(),
+		ItemSerial:   it.Serial(),
+		Price:        price,
+	}
+	if config.MarketListTime > 0 {
+		expires := time.Now().Add(time.Duration(config.MarketListTime) * time.Millisecond)
+		listing.Expires = expires.UnixMilli()
+	}
+	m.data.Listings = append(m.data.Listings, listing)
+	escrow.Hold(it)
+}
+
+// Remove removes listing with specified ID from the marketplace.
+func (m *market) Remove(id int) {
+	for i, l := range m.data.Listings {
+		if l.ID == id {
+			m.data.Listings = append(m.data.Listings[:i], m.data.Listings[i+1:]...)
+			return
+		}
+	}
+}
+
+// Return adds specified return for the user to collect.
+func (m *market) Return(ret res.MarketReturnData) {
+	m.data.Returns = append(m.data.Returns, ret)
+}
+
+// Returns returns all returns waiting to be collected by the user
+// with specified ID.
+func (m *market) Returns(userID string) (returns []res.MarketReturnData) {
+	for _, r := range m.data.Returns {
+		if r.UserID == userID {
+			returns = append(returns, r)
+		}
+	}
+	return
+}
+
+// SetReturns sets specified returns as returns waiting to be collected
+// by the user with specified ID.
+func (m *market) SetReturns(userID string, returns []res.MarketReturnData) {
+	var allReturns []res.MarketReturnData
+	for _, r := range m.data.Returns {
+		if r.UserID != userID {
+			allReturns = append(allReturns, r)
+		}
+	}
+	m.data.Returns = append(allReturns, returns...)
+}
+
+// Expire moves all expired listings to the returns of the sellers.
+// Returns true if any listing expired.
+func (m *market) Expire() bool {
+	var listings []res.MarketListingData
+	now := time.Now().UnixMilli()
+	for _, l := range m.data.Listings {
+		if l.Expires < 1 || l.Expires > now {
+			listings = append(listings, l)
+			continue
+		}
+		m.Return(res.MarketReturnData{
+			UserID:     l.UserID,
+			ItemID:     l.ItemID,
+			ItemSerial: l.ItemSerial,
+		})
+	}
+	expired := len(listings) < len(m.data.Listings)
+	m.data.Listings = listings
+	return expired
+}
+
+// Save saves marketplace data in the server data directory.
+func (m *market) Save() {
+	err := data.SaveMarket(config.MarketFile, m.data)
+	if err != nil {
+		log.Printf("Market: unable to save data: %v", err)
+	}
+}
+
+// expireMarketListings removes expired listings from the marketplace.
+func expireMarketListings() {
+	if marketplace.Expire() {
+		marketplace.Save()
+	}
+}
+
+// handleMarketSellRequest handles market-sell request.
+func handleMarketSellRequest(cli *Client, req request.MarketSell) error {
+	if len(config.MarketCurrency) < 1 {
+		return fmt.Errorf("Marketplace disabled")
+	}
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	if req.Price < 1 {
+		return fmt.Errorf("Invalid price: %d", req.Price)
+	}
+	seller, ok := game.Object(req.ObjectID, req.ObjectSerial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Seller not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	it := seller.Inventory().Item(req.ItemID, req.ItemSerial)
+	if it == nil {
+		return fmt.Errorf("Item not found: %s %s", req.ItemID, req.ItemSerial)
+	}
+	err := removeItems(seller, map[string][]string{it.ID(): {it.Serial()}})
+	if err != nil {
+		return fmt.Errorf("Unable to remove item: %v", err)
+	}
+	marketplace.Add(cli.User().ID(), seller, it, req.Price)
+	marketplace.Save()
+	return nil
+}
+
+// handleMarketSearchRequest handles market-search request.
+func handleMarketSearchRequest(cli *Client, req request.MarketSearch) []response.MarketListing {
+	return marketplace.Listings(req)
+}
+
+// handleMarketBuyRequest handles market-buy request.
+func handleMarketBuyRequest(cli *Client, req request.MarketBuy) error {
+	if len(config.MarketCurrency) < 1 {
+		return fmt.Errorf("Marketplace disabled")
+	}
+	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	listing := marketplace.Listing(req.ID)
+	if listing == nil {
+		return fmt.Errorf("Listing not found: %d", req.ID)
+	}
+	if listing.UserID == cli.User().ID() {
+		return fmt.Errorf("Unable to buy own listing: %d", req.ID)
+	}
+	buyer, ok := game.Object(req.ObjectID, req.ObjectSerial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Buyer not found: %s %s", req.ObjectID,
+			req.ObjectSerial)
+	}
+	price := currencyItems(buyer, listing.Price)
+	if price == nil {
+		return fmt.Errorf("Insufficient currency: %d", listing.Price)
+	}
+	it, err := escrow.Item(listing.ItemID, listing.ItemSerial)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve item: %v", err)
+	}
+	tx := newItemsTransaction()
+	err = tx.Insert(buyer, it)
+	if err != nil {
+		return fmt.Errorf("Invalid item: %v", err)
+	}
+	err = tx.Add(buyer, nil, price)
+	if err != nil {
+		return fmt.Errorf("Invalid currency: %v", err)
+	}
+	err = journalCommit(transferEntry, tx)
+	if err != nil {
+		return fmt.Errorf("Unable to buy item: %v", err)
+	}
+	fee := listing.Price * config.MarketFee / 100
+	marketplace.Return(res.MarketReturnData{
+		UserID:   listing.UserID,
+		Currency: listing.Price - fee,
+	})
+	escrow.Release(listing.ItemID, listing.ItemSerial)
+	marketplace.Remove(listing.ID)
+	marketplace.Save()
+	return nil
+}
+
+// handleMarketCancelRequest handles market-cancel request.
+func handleMarketCancelRequest(cli *Client, id int) error {
+	listing := marketplace.Listing(id)
+	if listing == nil {
+		return fmt.Errorf("Listing not found: %d", id)
+	}
+	if listing.UserID != cli.User().ID() {
+		return fmt.Errorf("Listing not owned: %d", id)
+	}
+	marketplace.Return(res.MarketReturnData{
+		UserID:     listing.UserID,
+		ItemID:     listing.ItemID,
+		ItemSerial: listing.ItemSerial,
+	})
+	marketplace.Remove(id)
+	marketplace.Save()
+	return nil
+}
+
+// handleMarketCollectRequest handles market-collect request.
+// All items and currency waiting for the client user are moved
+// to the inventory of specified character.
+func handleMarketCollectRequest(cli *Client, req request.Character) error {
+	if !cli.User().Controls(req.ID, req.Serial) {
+		return fmt.Errorf("Object not controlled: %s %s", req.ID, req.Serial)
+	}
+	char, ok := game.Object(req.ID, req.Serial).(*character.Character)
+	if !ok {
+		return fmt.Errorf("Character not found: %s %s", req.ID, req.Serial)
+	}
+	var left []res.MarketReturnData
+	var errs []string
+	for _, r := range marketplace.Returns(cli.User().ID()) {
+		err := collect(char, r)
+		if err != nil {
+			left = append(left, r)
+			errs = append(errs, err.Error())
+		}
+	}
+	marketplace.SetReturns(cli.User().ID(), left)
+	marketplace.Save()
+	if len(errs) > 0 {
+		return fmt.Errorf("Unable to collect all returns: %s",
+			strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// collect moves item or currency from specified marketplace return
+// to the inventory of specified character.
+func collect(char *character.Character, ret res.MarketReturnData) error {
+	tx := newItemsTransaction()
+	if len(ret.ItemID) > 0 {
+		it, err := escrow.Item(ret.ItemID, ret.ItemSerial)
+		if err != nil {
+			return err
+		}
+		err = tx.Insert(char, it)
+		if err != nil {
+			return fmt.Errorf("Invalid item: %v", err)
+		}
+	}
+	if ret.Currency > 0 {
+		data := flameres.Item(config.MarketCurrency)
+		if data == nil {
+			return fmt.Errorf("Currency data not found: %s", config.MarketCurrency)
+		}
+		var currency []item.Item
+		for i := 0; i < ret.Currency; i++ {
+			currency = append(currency, item.New(data))
+		}
+		err := tx.Insert(char, currency...)
+		if err != nil {
+			return fmt.Errorf("Invalid currency: %v", err)
+		}
+	}
+	err := journalCommit(transferEntry, tx)
+	if err != nil {
+		return fmt.Errorf("Unable to add items: %v", err)
+	}
+	if len(ret.ItemID) > 0 {
+		escrow.Release(ret.ItemID, ret.ItemSerial)
+	}
+	return nil
+}
+
+// currencyItems returns specified amount of currency items from
+// the inventory of specified character, or nil if the character
+// doesn't have enough currency.
+// Items are in the form of a map with IDs as keys and serial values as values.
+func currencyItems(char *character.Character, amount int) map[string][]string {
+	var serials []string
+	for _, it := range char.Inventory().Items() {
+		if len(serials) >= amount {
+			break
+		}
+		if it.ID() == config.MarketCurrency {
+			serials = append(serials, it.Serial())
+		}
+	}
+	if len(serials) < amount {
+		return nil
+	}
+	return map[string][]string{config.MarketCurrency: serials}
+}
@@ -0,0 +1,242 @@
+/*
+ * merchant.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/isangeles/flame/character"
+	flameres "github.com/isangeles/flame/data/res"
+	"github.com/isangeles/flame/item"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+)
+
+// Struct for NPC merchants.
+type merchants struct {
+	merchants map[string]res.MerchantData
+	restocks  map[string]time.Time
+}
+
+// newMerchants creates merchants from specified data.
+func newMerchants(data ...res.MerchantData) *merchants {
+	m := merchants{
+		merchants: make(map[string]res.MerchantData),
+		restocks:  make(map[string]time.Time),
+	}
+	for _, d := range data {
+		for _, id := range d.Chars {
+			m.merchants[id] = d
+		}
+	}
+	return &m
+}
+
+// Merchant returns merchant data for character with specified
+// ID, or nil if the character is not a merchant.
+func (m *merchants) Merchant(id string) *res.MerchantData {
+	merchant, ok := m.merchants[id]
+	if !ok {
+		return nil
+	}
+	return &merchant
+}
+
+// Restock adds missing stock items to inventories of all
+// merchants from specified characters.
+// Merchants are restocked after restock time specified in
+// the merchant data.
+func (m *merchants) Restock(chars []*character.Character) {
+	for _, c := range chars {
+		merchant := m.Merchant(c.ID())
+		if merchant == nil || merchant.RestockTime < 1 {
+			continue
+		}
+		restockTime := time.Duration(merchant.RestockTime) * time.Millisecond
+		lastRestock, ok := m.restocks[c.ID()+c.Serial()]
+		if ok && time.Since(lastRestock) < restockTime {
+			continue
+		}
+		m.restocks[c.ID()+c.Serial()] = time.Now()
+		restock(c, merchant)
+	}
+}
+
+// restock adds missing stock items to inventory of specified
+// merchant character.
+// Added items are recorded in the journal, so the items sold
+// after the restock can be restored by the journal replay.
+func restock(char *character.Character, merchant *res.MerchantData) {
+	tx := newItemsTransaction()
+	for _, id := range merchant.Stock {
+		count := stockCount(char, id)
+		data := flameres.Item(id)
+		if data == nil {
+			log.Printf("Merchant: %s: stock item data not found: %s",
+				merchant.ID, id)
+			continue
+		}
+		var items []item.Item
+		for i := count; i < merchant.StockSize; i++ {
+			items = append(items, item.New(data))
+		}
+		if len(items) < 1 {
+			continue
+		}
+		err := tx.Insert(char, items...)
+		if err != nil {
+			log.Printf("Merchant: %s: invalid stock items: %v", merchant.ID, err)
+			return
+		}
+	}
+	if len(tx.Transfers()) < 1 {
+		return
+	}
+	err := journalCommit(transferEntry, tx)
+	if err != nil {
+		log.Printf("Merchant: %s: unable to restock: %v", merchant.ID, err)
+	}
+}
+
+// stockCount returns the number of items with specified ID
+// in the inventory of specified container.
+func stockCount(con item.Container, id string) (count int) {
+	for _, it := range con.Inventory().Items() {
+		if it.ID() == id {
+			count++
+		}
+	}
+	return
+}
+
+// Accepts checks if specified merchant accepts trade with specified
+// items. Merchant accepts trade only if the value of items to sell
+// is equal to or greater than the value of items to buy, and
+// if the merchant would not hold more items with the same ID than
+// the stock size specified in the merchant data.
+func (m *merchants) Accepts(merchant *res.MerchantData, seller, buyer item.Container,
+	req request.Trade) error {
+	buyValue, err := itemsValue(seller, req.Buy.Items, merchant.SellMod)
+	if err != nil {
+		return fmt.Errorf("Invalid items to buy: %v", err)
+	}
+	sellValue, err := itemsValue(buyer, req.Sell.Items, merchant.BuyMod)
+	if err != nil {
+		return fmt.Errorf("Invalid items to sell: %v", err)
+	}
+	if sellValue < buyValue {
+		return fmt.Errorf("Insufficient value: %d < %d", sellValue, buyValue)
+	}
+	if merchant.StockSize < 1 {
+		return nil
+	}
+	for id, serials := range req.Sell.Items {
+		count := stockCount(seller, id) + len(serials) - len(req.Buy.Items[id])
+		if count > merchant.StockSize {
+			return fmt.Errorf("Stock full: %s: %d > %d", id, count,
+				merchant.StockSize)
+		}
+	}
+	return nil
+}
+
+// itemsValue returns the sum of values of specified items from specified
+// container, multiplied by specified value modifier.
+// Items are in the form of a map with IDs as keys and serial values as values.
+func itemsValue(con item.Container, items map[string][]string, mod float64) (int, error) {
+	value := 0.0
+	for id, serials := range items {
+		for _, serial := range serials {
+			it := con.Inventory().Item(id, serial)
+			if it == nil {
+				return 0, fmt.Errorf("Item not found: %s %s", id, serial)
+			}
+			value += float64(it.Value()) * mod
+		}
+	}
+	return int(math.Round(value)), nil
+}
+
+// isMerchantTrade checks if seller from specified trade request
+// is a merchant.
+func isMerchantTrade(req request.Trade) bool {
+	return game.merchants.Merchant(req.Sell.ObjectToID) != nil
+}
+
+// handleMerchantTradeRequest handles trade request with a merchant
+// as a seller.
+// Trade is accepted or rejected by the merchant immediately, without
+// confirmation from the seller owner.
+func handleMerchantTradeRequest(cli *Client, req request.Trade) (resp response.TradeCompleted, err error) {
+	// Check if client controls buyer.
+	if !cli.User().Controls(req.Buy.ObjectToID, req.Buy.ObjectToSerial) {
+		err = fmt.Errorf("Object not controlled: %s %s", req.Buy.ObjectToID,
+			req.Buy.ObjectToSerial)
+		return
+	}
+	// Find seller & buyer.
+	seller, ok := game.Object(req.Sell.ObjectToID, req.Sell.ObjectToSerial).(*character.Character)
+	if !ok {
+		err = fmt.Errorf("Seller not found: %s %s", req.Sell.ObjectToID,
+			req.Sell.ObjectToSerial)
+		return
+	}
+	buyer, ok := game.Object(req.Buy.ObjectToID, req.Buy.ObjectToSerial).(*character.Character)
+	if !ok {
+		err = fmt.Errorf("Buyer not found: %s %s", req.Buy.ObjectToID,
+			req.Buy.ObjectToSerial)
+		return
+	}
+	// Check if merchant accepts trade.
+	merchant := game.merchants.Merchant(seller.ID())
+	err = game.merchants.Accepts(merchant, seller, buyer, req)
+	if err != nil {
+		err = fmt.Errorf("Merchant rejected trade: %v", err)
+		return
+	}
+	return handleConfirmedTradeRequest(cli, req)
+}
+
+// loadMerchants loads NPC merchants from the module server
+// directory.
+func (g *Game) loadMerchants() error {
+	g.merchants = newMerchants()
+	path := filepath.Join(g.Conf().Path, config.ModuleServerPath, "merchants")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	merchantsData, err := data.ImportMerchants(path)
+	if err != nil {
+		return fmt.Errorf("unable to import merchants: %v", err)
+	}
+	g.merchants = newMerchants(merchantsData...)
+	return nil
+}
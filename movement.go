@@ -0,0 +1,268 @@
+/*
+ * movement.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/isangeles/flame/area"
+	"github.com/isangeles/flame/character"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/nav"
+)
+
+// Distance from waypoint at which the waypoint
+// is treated as reached.
+const waypointRange = 1.0
+
+// Name of the TMX map file in the area directory.
+const areaMapFile = "map.tmx"
+
+// Struct for tracking movement of game characters.
+type movementTracker struct {
+	mutex     sync.Mutex
+	positions map[string]trackedPosition
+	lastCheck time.Time
+}
+
+// Struct for tracked character position.
+type trackedPosition struct {
+	X, Y float64
+	Time time.Time
+}
+
+// newMovementTracker creates new movement tracker.
+func newMovementTracker() *movementTracker {
+	mt := movementTracker{
+		positions: make(map[string]trackedPosition),
+		lastCheck: time.Now(),
+	}
+	return &mt
+}
+
+// Reset sets current position of specified character as
+// its last tracked position.
+// Should be called after each legitimate position change,
+// like teleport or respawn.
+func (mt *movementTracker) Reset(char *character.Character) {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+	x, y := char.Position()
+	mt.positions[char.ID()+char.Serial()] = trackedPosition{x, y, time.Now()}
+}
+
+// Check checks if position change of characters returned by specified
+// function since the last check doesn't exceed the movement speed of
+// the character multiplied by the move speed tolerance from the config
+// package, and logs all suspicious characters.
+// The check is performed at most once per second.
+// Should be called by the server update loop, as the characters
+// of users are modified by the handlers of client requests.
+// Returns all suspicious characters.
+func (mt *movementTracker) Check(chars func() []*character.Character) (suspects []*character.Character) {
+	config.RLock()
+	tolerance := config.MoveSpeedTolerance
+	config.RUnlock()
+	if tolerance <= 0 || time.Since(mt.lastCheck) < time.Second {
+		return
+	}
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+	mt.lastCheck = time.Now()
+	positions := make(map[string]trackedPosition)
+	for _, c := range chars() {
+		x, y := c.Position()
+		pos := trackedPosition{x, y, time.Now()}
+		positions[c.ID()+c.Serial()] = pos
+		last, ok := mt.positions[c.ID()+c.Serial()]
+		if !ok {
+			continue
+		}
+		dist := math.Hypot(pos.X-last.X, pos.Y-last.Y)
+		speed := float64(c.Attributes().MoveMod) * tolerance
+		maxDist := speed * pos.Time.Sub(last.Time).Seconds()
+		if dist > maxDist {
+			log.Printf("Movement: suspicious position change: %s %s: %f > %f",
+				c.ID(), c.Serial(), dist, maxDist)
+			suspects = append(suspects, c)
+		}
+	}
+	mt.positions = positions
+	return
+}
+
+// loadAreaMaps loads tile maps of all areas in the current chapter.
+// Areas without map file are skipped.
+func (g *Game) loadAreaMaps() {
+	g.areaMaps = make(map[string]res.AreaMapData)
+	var areas []*area.Area
+	for _, a := range g.Chapter().Areas() {
+		areas = append(areas, a)
+		areas = append(areas, a.AllSubareas()...)
+	}
+	for _, a := range areas {
+		path := filepath.Join(g.Conf().ChaptersPath(), g.Chapter().Conf().ID, "areas",
+			a.ID(), areaMapFile)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		areaMap, err := data.ImportAreaMap(path)
+		if err != nil {
+			log.Printf("Game: unable to load area map: %s: %v", a.ID(), err)
+			continue
+		}
+		g.areaMaps[a.ID()] = areaMap
+	}
+}
+
+// areaGrid returns search grid for specified area.
+// The grid matches the area tile map, or, for areas without map,
+// is an unbounded grid with path cell size from the config package.
+func (g *Game) areaGrid(a *area.Area) nav.Grid {
+	m, ok := g.areaMaps[a.ID()]
+	if !ok {
+		config.RLock()
+		defer config.RUnlock()
+		return nav.Grid{CellWidth: config.PathCellSize, CellHeight: config.PathCellSize}
+	}
+	grid := nav.Grid{
+		CellWidth:  float64(m.TileWidth),
+		CellHeight: float64(m.TileHeight),
+		Width:      m.Width,
+		Height:     m.Height,
+	}
+	return grid
+}
+
+// validPosition checks if specified position is inside bounds of
+// the specified area map and is passable.
+// Only negative positions are out of bounds for areas without map.
+func (g *Game) validPosition(a *area.Area, x, y float64) error {
+	if x < 0 || y < 0 || math.IsNaN(x) || math.IsNaN(y) ||
+		math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return fmt.Errorf("Position out of area bounds: %f %f", x, y)
+	}
+	if m, ok := g.areaMaps[a.ID()]; ok {
+		width := float64(m.Width * m.TileWidth)
+		height := float64(m.Height * m.TileHeight)
+		if x >= width || y >= height {
+			return fmt.Errorf("Position out of area bounds: %f %f: area size: %f %f",
+				x, y, width, height)
+		}
+	}
+	if !a.Passable(x, y) {
+		return fmt.Errorf("Position not passable: %f %f", x, y)
+	}
+	return nil
+}
+
+// Struct for paths followed by game characters.
+type charPaths struct {
+	mutex sync.Mutex
+	paths map[string]*charPath
+}
+
+// Struct for path of game character.
+type charPath struct {
+	char   *character.Character
+	points []nav.Point
+}
+
+// newCharPaths creates new container for
+// characters paths.
+func newCharPaths() *charPaths {
+	cp := charPaths{paths: make(map[string]*charPath)}
+	return &cp
+}
+
+// Set sets specified points as path to follow for specified
+// character and moves the character to the first point.
+func (cp *charPaths) Set(char *character.Character, points []nav.Point) {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	if len(points) < 1 {
+		delete(cp.paths, char.ID()+char.Serial())
+		return
+	}
+	cp.paths[char.ID()+char.Serial()] = &charPath{char, points}
+	char.SetDestPoint(points[0].X, points[0].Y)
+}
+
+// Clear removes path of specified character.
+func (cp *charPaths) Clear(char *character.Character) {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	delete(cp.paths, char.ID()+char.Serial())
+}
+
+// Path returns remaining points of the path of specified
+// character.
+func (cp *charPaths) Path(char *character.Character) []nav.Point {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	path := cp.paths[char.ID()+char.Serial()]
+	if path == nil {
+		return nil
+	}
+	return append([]nav.Point{}, path.points...)
+}
+
+// Follow moves all characters with paths to their next
+// waypoint after reaching the current one.
+func (cp *charPaths) Follow() {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	for key, path := range cp.paths {
+		x, y := path.char.Position()
+		next := path.points[0]
+		if math.Hypot(next.X-x, next.Y-y) > waypointRange {
+			continue
+		}
+		path.points = path.points[1:]
+		if len(path.points) < 1 {
+			delete(cp.paths, key)
+			continue
+		}
+		path.char.SetDestPoint(path.points[0].X, path.points[0].Y)
+	}
+}
+
+// findPath searches for path for specified character from its
+// current position to specified destination point.
+func findPath(char *character.Character, a *area.Area, x, y float64) ([]nav.Point, error) {
+	posX, posY := char.Position()
+	from := nav.Point{posX, posY}
+	to := nav.Point{x, y}
+	passable := func(x, y float64) bool {
+		return game.validPosition(a, x, y) == nil
+	}
+	return nav.FindPath(from, to, game.areaGrid(a), passable)
+}
@@ -0,0 +1,228 @@
+/*
+ * nav.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package with pathfinding for game characters.
+package nav
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+)
+
+// Maximal number of grid cells visited during
+// a single path search on unbounded grid, or on a grid
+// with more cells.
+const MaxNodes = 20000
+
+// Struct for path point.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Type for function that checks if position
+// is passable.
+type PassableFunc func(x, y float64) bool
+
+// Struct for search grid, e.g. area tile map.
+// Width and height are numbers of grid cells, cells
+// outside the grid are not passable.
+// Grid without width or height is unbounded.
+type Grid struct {
+	CellWidth  float64
+	CellHeight float64
+	Width      int
+	Height     int
+}
+
+// Struct for grid cell.
+type cell struct {
+	X, Y int
+}
+
+// Struct for search node.
+type node struct {
+	cell   cell
+	cost   float64
+	score  float64
+	parent *node
+	index  int
+}
+
+// FindPath searches for a path between specified points using the A*
+// algorithm on specified grid.
+// Returns a list of waypoints without the start point, where the last
+// waypoint is always the destination point.
+// Returns an error if the destination point is not reachable.
+func FindPath(from, to Point, grid Grid, passable PassableFunc) ([]Point, error) {
+	if grid.CellWidth <= 0 || grid.CellHeight <= 0 {
+		return nil, fmt.Errorf("invalid cell size: %f %f", grid.CellWidth,
+			grid.CellHeight)
+	}
+	if !passable(to.X, to.Y) {
+		return nil, fmt.Errorf("destination not passable")
+	}
+	start := grid.pointCell(from)
+	goal := grid.pointCell(to)
+	if !grid.contains(goal) {
+		return nil, fmt.Errorf("destination out of grid bounds")
+	}
+	if start == goal {
+		return []Point{to}, nil
+	}
+	open := new(nodeQueue)
+	heap.Push(open, &node{cell: start, score: heuristic(start, goal)})
+	nodes := map[cell]*node{start: (*open)[0]}
+	closed := make(map[cell]bool)
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*node)
+		if current.cell == goal {
+			return pathPoints(current, to, grid), nil
+		}
+		closed[current.cell] = true
+		if len(closed) > MaxNodes {
+			return nil, fmt.Errorf("search limit reached")
+		}
+		for _, n := range neighbours(current.cell, grid, passable) {
+			if closed[n] {
+				continue
+			}
+			cost := current.cost + heuristic(current.cell, n)
+			next, ok := nodes[n]
+			if ok && cost >= next.cost {
+				continue
+			}
+			if !ok {
+				next = &node{cell: n}
+				nodes[n] = next
+			}
+			next.cost = cost
+			next.score = cost + heuristic(n, goal)
+			next.parent = current
+			if ok && next.index >= 0 {
+				heap.Fix(open, next.index)
+				continue
+			}
+			heap.Push(open, next)
+		}
+	}
+	return nil, fmt.Errorf("destination not reachable")
+}
+
+// neighbours returns passable neighbour cells of specified cell.
+// Diagonal moves are allowed only if both adjacent cells are
+// passable.
+func neighbours(c cell, grid Grid, passable PassableFunc) (cells []cell) {
+	pass := func(c cell) bool {
+		if !grid.contains(c) {
+			return false
+		}
+		p := grid.cellPoint(c)
+		return passable(p.X, p.Y)
+	}
+	for x := -1; x <= 1; x++ {
+		for y := -1; y <= 1; y++ {
+			if x == 0 && y == 0 {
+				continue
+			}
+			n := cell{c.X + x, c.Y + y}
+			if !pass(n) {
+				continue
+			}
+			if x != 0 && y != 0 && (!pass(cell{c.X + x, c.Y}) || !pass(cell{c.X, c.Y + y})) {
+				continue
+			}
+			cells = append(cells, n)
+		}
+	}
+	return
+}
+
+// pathPoints creates list of waypoints from path that
+// ends with specified node.
+// Waypoints on a straight line are skipped.
+func pathPoints(end *node, to Point, grid Grid) []Point {
+	var cells []cell
+	for n := end; n != nil; n = n.parent {
+		cells = append([]cell{n.cell}, cells...)
+	}
+	var points []Point
+	for i := 1; i < len(cells)-1; i++ {
+		prev, c, next := cells[i-1], cells[i], cells[i+1]
+		if c.X-prev.X == next.X-c.X && c.Y-prev.Y == next.Y-c.Y {
+			continue
+		}
+		points = append(points, grid.cellPoint(c))
+	}
+	return append(points, to)
+}
+
+// heuristic returns octile distance between specified cells.
+func heuristic(a, b cell) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dy := math.Abs(float64(a.Y - b.Y))
+	return dx + dy + (math.Sqrt2-2)*math.Min(dx, dy)
+}
+
+// pointCell returns grid cell for specified point.
+func (g Grid) pointCell(p Point) cell {
+	return cell{int(math.Floor(p.X / g.CellWidth)), int(math.Floor(p.Y / g.CellHeight))}
+}
+
+// cellPoint returns center point of specified cell.
+func (g Grid) cellPoint(c cell) Point {
+	return Point{(float64(c.X) + 0.5) * g.CellWidth, (float64(c.Y) + 0.5) * g.CellHeight}
+}
+
+// contains checks if specified cell is inside the grid bounds.
+func (g Grid) contains(c cell) bool {
+	if g.Width < 1 || g.Height < 1 {
+		return true
+	}
+	return c.X >= 0 && c.Y >= 0 && c.X < g.Width && c.Y < g.Height
+}
+
+// Type for priority queue of search nodes.
+type nodeQueue []*node
+
+func (q nodeQueue) Len() int { return len(q) }
+
+func (q nodeQueue) Less(i, j int) bool { return q[i].score < q[j].score }
+
+func (q nodeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *nodeQueue) Push(x interface{}) {
+	n := x.(*node)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	n.index = -1
+	*q = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,96 @@
+/*
+ * nav_test.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package nav
+
+import (
+	"testing"
+)
+
+// TestFindPath tests searching for path around obstacle.
+func TestFindPath(t *testing.T) {
+	// Wall between start and destination with a gap at the bottom.
+	passable := func(x, y float64) bool {
+		if x < 0 || y < 0 || x > 100 || y > 100 {
+			return false
+		}
+		return x < 40 || x > 60 || y > 80
+	}
+	from := Point{10, 10}
+	to := Point{90, 10}
+	grid := Grid{CellWidth: 10, CellHeight: 10, Width: 10, Height: 10}
+	path, err := FindPath(from, to, grid, passable)
+	if err != nil {
+		t.Fatalf("Unable to find path: %v", err)
+	}
+	if len(path) < 2 {
+		t.Fatalf("Invalid path length: %d", len(path))
+	}
+	if path[len(path)-1] != to {
+		t.Errorf("Invalid last waypoint: %v != %v", path[len(path)-1], to)
+	}
+	for _, p := range path {
+		if !passable(p.X, p.Y) {
+			t.Errorf("Waypoint not passable: %v", p)
+		}
+	}
+}
+
+// TestFindPathUnreachable tests searching for path to
+// unreachable destination.
+func TestFindPathUnreachable(t *testing.T) {
+	passable := func(x, y float64) bool {
+		if x < 0 || y < 0 || x > 100 || y > 100 {
+			return false
+		}
+		return x < 40 || x > 60
+	}
+	grid := Grid{CellWidth: 10, CellHeight: 10, Width: 10, Height: 10}
+	_, err := FindPath(Point{10, 10}, Point{90, 10}, grid, passable)
+	if err == nil {
+		t.Errorf("No error for unreachable destination")
+	}
+	_, err = FindPath(Point{10, 10}, Point{50, 10}, grid, passable)
+	if err == nil {
+		t.Errorf("No error for not passable destination")
+	}
+}
+
+// TestFindPathBounds tests if the path search is limited
+// by the grid bounds.
+func TestFindPathBounds(t *testing.T) {
+	calls := 0
+	passable := func(x, y float64) bool {
+		calls++
+		return x < 40 || x > 60
+	}
+	grid := Grid{CellWidth: 10, CellHeight: 10, Width: 10, Height: 10}
+	_, err := FindPath(Point{10, 10}, Point{90, 10}, grid, passable)
+	if err == nil {
+		t.Errorf("No error for unreachable destination")
+	}
+	if calls > grid.Width*grid.Height*8+1 {
+		t.Errorf("Search was not limited by grid bounds: %d passable calls", calls)
+	}
+	_, err = FindPath(Point{10, 10}, Point{150, 10}, grid, passable)
+	if err == nil {
+		t.Errorf("No error for destination out of grid bounds")
+	}
+}
@@ -0,0 +1,298 @@
+/*
+ * newchar.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	flameres "github.com/isangeles/flame/data/res"
+
+	"github.com/isangeles/fire/request"
+)
+
+const newCharPolicyFile = ".newchar"
+
+// Type for new character validation rule.
+// Rule returns an error with a violated rule
+// description.
+type newCharRule func(g *Game, req request.NewChar) error
+
+// Rules for new characters validation.
+var newCharRules = []newCharRule{
+	validNewCharID,
+	validNewCharName,
+	validNewCharLevel,
+	validNewCharAttributes,
+	validNewCharRace,
+	validNewCharSex,
+	validNewCharAlignment,
+	validNewCharItems,
+	validNewCharEquipment,
+	validNewCharSkills,
+	validNewCharEffects,
+	validNewCharFlags,
+}
+
+// ValidNewCharacter checks if specified new character request is valid
+// for the new character in the current chapter.
+// Returns an error with the first violated rule.
+func (g *Game) ValidNewCharacter(req request.NewChar) error {
+	for _, rule := range newCharRules {
+		err := rule(g, req)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validNewCharID checks if ID and serial value of the new
+// character are unique.
+func validNewCharID(g *Game, req request.NewChar) error {
+	if len(req.Data.ID) < 1 {
+		return fmt.Errorf("id: character ID is empty")
+	}
+	if g.Object(req.Data.ID, req.Data.Serial) != nil {
+		return fmt.Errorf("id: object already exists: %s %s", req.Data.ID,
+			req.Data.Serial)
+	}
+	for _, c := range g.Resources().Characters {
+		if c.ID == req.Data.ID {
+			return fmt.Errorf("id: character ID already taken: %s", req.Data.ID)
+		}
+	}
+	for _, c := range g.Chapter().Resources().Characters {
+		if c.ID == req.Data.ID {
+			return fmt.Errorf("id: character ID already taken: %s", req.Data.ID)
+		}
+	}
+	return nil
+}
+
+// validNewCharName checks if the name of the new character
+// matches the name rules from new character policy.
+func validNewCharName(g *Game, req request.NewChar) error {
+	policy := g.newCharPolicy
+	name := strings.TrimSpace(req.Name)
+	nameLen := utf8.RuneCountInString(name)
+	if nameLen < 1 {
+		return fmt.Errorf("name: name is empty")
+	}
+	if policy.NameMinLen > 0 && nameLen < policy.NameMinLen {
+		return fmt.Errorf("name: name too short: min %d", policy.NameMinLen)
+	}
+	if policy.NameMaxLen > 0 && nameLen > policy.NameMaxLen {
+		return fmt.Errorf("name: name too long: max %d", policy.NameMaxLen)
+	}
+	if len(policy.NamePattern) > 0 {
+		match, err := regexp.MatchString(policy.NamePattern, name)
+		if err != nil {
+			return fmt.Errorf("name: invalid name pattern: %v", err)
+		}
+		if !match {
+			return fmt.Errorf("name: name doesn't match pattern: %s", policy.NamePattern)
+		}
+	}
+	for _, w := range policy.NameBlacklist {
+		if len(w) > 0 && strings.Contains(strings.ToLower(name), strings.ToLower(w)) {
+			return fmt.Errorf("name: name contains forbidden word")
+		}
+	}
+	return nil
+}
+
+// validNewCharLevel checks if the level of the new character
+// is not higher than the start level of the current chapter.
+func validNewCharLevel(g *Game, req request.NewChar) error {
+	if req.Data.Level < 1 {
+		return fmt.Errorf("level: level lower than 1")
+	}
+	if req.Data.Level > g.Chapter().Conf().StartLevel {
+		return fmt.Errorf("level: level higher than start level: %d",
+			g.Chapter().Conf().StartLevel)
+	}
+	return nil
+}
+
+// validNewCharAttributes checks if the new character attributes
+// don't exceed the attributes budget.
+// The budget from new character policy takes precedence over the
+// start attributes of the current chapter.
+func validNewCharAttributes(g *Game, req request.NewChar) error {
+	attrs := req.Data.Attributes
+	for _, a := range []int{attrs.Str, attrs.Con, attrs.Dex, attrs.Int, attrs.Wis} {
+		if a < 0 {
+			return fmt.Errorf("attributes: negative attribute value")
+		}
+	}
+	budget := g.Chapter().Conf().StartAttrs
+	if g.newCharPolicy.AttrsBudget > 0 {
+		budget = g.newCharPolicy.AttrsBudget
+	}
+	sum := attrs.Str + attrs.Con + attrs.Dex + attrs.Int + attrs.Wis
+	if sum > budget {
+		return fmt.Errorf("attributes: attributes points exceed budget: %d > %d",
+			sum, budget)
+	}
+	return nil
+}
+
+// validNewCharRace checks if the race of the new character is
+// allowed by the new character policy.
+func validNewCharRace(g *Game, req request.NewChar) error {
+	if !allowed(g.newCharPolicy.Races, req.Data.Race) {
+		return fmt.Errorf("race: race not allowed: %s", req.Data.Race)
+	}
+	return nil
+}
+
+// validNewCharSex checks if the sex of the new character is
+// allowed by the new character policy.
+func validNewCharSex(g *Game, req request.NewChar) error {
+	if !allowed(g.newCharPolicy.Sexes, req.Data.Sex) {
+		return fmt.Errorf("sex: sex not allowed: %s", req.Data.Sex)
+	}
+	return nil
+}
+
+// validNewCharAlignment checks if the alignment of the new character
+// is allowed by the new character policy.
+func validNewCharAlignment(g *Game, req request.NewChar) error {
+	if !allowed(g.newCharPolicy.Alignments, req.Data.Alignment) {
+		return fmt.Errorf("alignment: alignment not allowed: %s",
+			req.Data.Alignment)
+	}
+	return nil
+}
+
+// validNewCharItems checks if the new character has only items
+// from start items of the current chapter, and no more items with
+// the same ID than start items.
+func validNewCharItems(g *Game, req request.NewChar) error {
+	counts := make(map[string]int)
+	for _, i := range req.Data.Inventory.Items {
+		if !contains(g.Chapter().Conf().StartItems, i.ID) {
+			return fmt.Errorf("items: item not allowed: %s", i.ID)
+		}
+		counts[i.ID]++
+		if counts[i.ID] > occurrences(g.Chapter().Conf().StartItems, i.ID) {
+			return fmt.Errorf("items: too many items: %s", i.ID)
+		}
+	}
+	return nil
+}
+
+// validNewCharEquipment checks if the new character has only
+// equipped items from the character inventory.
+func validNewCharEquipment(g *Game, req request.NewChar) error {
+outer:
+	for _, eqi := range req.Data.Equipment.Items {
+		for _, i := range req.Data.Inventory.Items {
+			if i.ID == eqi.ID && i.Serial == eqi.Serial {
+				continue outer
+			}
+		}
+		return fmt.Errorf("equipment: equipped item not in inventory: %s %s",
+			eqi.ID, eqi.Serial)
+	}
+	return nil
+}
+
+// validNewCharSkills checks if the new character has only skills
+// from start skills of the current chapter, and no more skills with
+// the same ID than start skills.
+func validNewCharSkills(g *Game, req request.NewChar) error {
+	counts := make(map[string]int)
+	for _, s := range req.Data.Skills {
+		if !contains(g.Chapter().Conf().StartSkills, s.ID) {
+			return fmt.Errorf("skills: skill not allowed: %s", s.ID)
+		}
+		counts[s.ID]++
+		if counts[s.ID] > occurrences(g.Chapter().Conf().StartSkills, s.ID) {
+			return fmt.Errorf("skills: too many skills: %s", s.ID)
+		}
+	}
+	return nil
+}
+
+// validNewCharEffects checks if the new character has no
+// effects.
+func validNewCharEffects(g *Game, req request.NewChar) error {
+	if len(req.Data.Effects) > 0 {
+		return fmt.Errorf("effects: new character can't have effects")
+	}
+	return nil
+}
+
+// validNewCharFlags checks if the new character has no flags.
+// User flags are added to the character by the server.
+func validNewCharFlags(g *Game, req request.NewChar) error {
+	if len(req.Data.Flags) > 0 {
+		return fmt.Errorf("flags: new character can't have flags")
+	}
+	return nil
+}
+
+// newCharData returns data for the new character from specified
+// valid request data.
+// Serial values of inventory items are removed, so items of the new
+// character are created with serial values assigned by the server.
+// Equipment is removed too, as it refers to the items by serial values.
+func newCharData(data flameres.CharacterData) flameres.CharacterData {
+	items := data.Inventory.Items[:0:0]
+	for _, i := range data.Inventory.Items {
+		i.Serial = ""
+		items = append(items, i)
+	}
+	data.Inventory.Items = items
+	data.Equipment.Items = nil
+	return data
+}
+
+// allowed checks if specified value is on the list of allowed
+// values. Empty list allows all values.
+func allowed(values []string, value string) bool {
+	return len(values) < 1 || contains(values, value)
+}
+
+// occurrences returns the number of occurrences of specified value
+// on specified list.
+func occurrences(values []string, value string) (n int) {
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return
+}
+
+// contains checks if specified list contains specified value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,531 @@
+/*
+ * party.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/isangeles/flame/character"
+	"github.com/isangeles/flame/item"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+	"github.com/isangeles/fire/user"
+)
+
+const (
+	// Time for party members to roll for an item.
+	partyRollTime = 30 * time.Second
+	// Time for invited users to accept a party invite.
+	partyInviteTime = 60 * time.Second
+)
+
+var (
+	parties     = make(map[int]*party)
+	lastPartyID = 0
+	partyRolls  = make(map[int]*partyRoll)
+	lastRollID  = 0
+)
+
+// Struct for party of users.
+type party struct {
+	ID      int
+	Leader  string
+	Members []string
+	Invites map[string]time.Time
+	Loot    string
+	next    int
+}
+
+// Struct for party roll for a loot item.
+type partyRoll struct {
+	ID         int
+	PartyID    int
+	FromID     string
+	FromSerial string
+	ItemID     string
+	ItemSerial string
+	Choices    map[string]request.PartyRoll
+	Time       time.Time
+}
+
+// userParty returns party of the user with specified ID, or nil
+// if the user is not a party member.
+func userParty(id string) *party {
+	for _, p := range parties {
+		if p.Member(id) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Member checks if user with specified ID is a member of the party.
+func (p *party) Member(id string) bool {
+	for _, m := range p.Members {
+		if m == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes user with specified ID from the party.
+// If the user was a party leader, the next member becomes a new
+// leader. The party is disbanded if there is only one member left.
+func (p *party) Remove(id string) {
+	for i, m := range p.Members {
+		if m == id {
+			p.Members = append(p.Members[:i], p.Members[i+1:]...)
+			break
+		}
+	}
+	if len(p.Members) < 2 {
+		delete(parties, p.ID)
+		return
+	}
+	if p.Leader == id {
+		p.Leader = p.Members[0]
+	}
+}
+
+// RemoveInvite removes invite for user with specified ID.
+// The party is disbanded if there is only one member and no
+// pending invites left.
+func (p *party) RemoveInvite(id string) {
+	delete(p.Invites, id)
+	if len(p.Members) < 2 && len(p.Invites) < 1 {
+		delete(parties, p.ID)
+	}
+}
+
+// Notify sends specified response to all party members.
+// Member IDs are copied before the response is sent in
+// the background, so the party can be modified right after
+// the call.
+func (p *party) Notify(resp response.Response) {
+	members := append([]string(nil), p.Members...)
+	sendResp := func() {
+		for _, m := range members {
+			userResponses <- userResponse{Response: resp, UserID: m}
+		}
+	}
+	go sendResp()
+}
+
+// Response returns party response for the party.
+func (p *party) Response() response.Party {
+	return response.Party{
+		ID:      p.ID,
+		Leader:  p.Leader,
+		Members: append([]string{}, p.Members...),
+		Loot:    p.Loot,
+	}
+}
+
+// partyResponse returns party response for specified user.
+func partyResponse(usr *user.User) response.Party {
+	if usr == nil {
+		return response.Party{}
+	}
+	p := userParty(usr.ID())
+	if p == nil {
+		return response.Party{}
+	}
+	return p.Response()
+}
+
+// partyUsers returns all other members of the party of
+// specified user.
+func partyUsers(usr *user.User) (users []*user.User) {
+	p := userParty(usr.ID())
+	if p == nil {
+		return
+	}
+	for _, m := range p.Members {
+		if m == usr.ID() {
+			continue
+		}
+		member := data.User(m)
+		if member != nil {
+			users = append(users, member)
+		}
+	}
+	return
+}
+
+// leaveParty removes specified user from the user party.
+func leaveParty(usr *user.User) {
+	p := userParty(usr.ID())
+	if p == nil {
+		return
+	}
+	p.Remove(usr.ID())
+}
+
+// handlePartyInviteRequest handles party-invite request.
+// A new party is created if the client user is not a party member.
+func handlePartyInviteRequest(cli *Client, id string) error {
+	invited := data.User(id)
+	if invited == nil || !invited.Logged {
+		return fmt.Errorf("User not found: %s", id)
+	}
+	if id == cli.User().ID() {
+		return fmt.Errorf("Unable to invite self")
+	}
+	if userParty(id) != nil {
+		return fmt.Errorf("User is already in party: %s", id)
+	}
+	p := userParty(cli.User().ID())
+	size := 1
+	if p != nil {
+		if p.Leader != cli.User().ID() {
+			return fmt.Errorf("Not a party leader")
+		}
+		size = len(p.Members) + len(p.Invites)
+	}
+	if config.PartyMaxSize > 0 && size >= config.PartyMaxSize {
+		return fmt.Errorf("Party is full")
+	}
+	if p == nil {
+		lastPartyID++
+		p = &party{
+			ID:      lastPartyID,
+			Leader:  cli.User().ID(),
+			Members: []string{cli.User().ID()},
+			Invites: make(map[string]time.Time),
+			Loot:    request.LootFree,
+		}
+		parties[p.ID] = p
+	}
+	p.Invites[id] = time.Now()
+	invite := response.PartyInvite{ID: p.ID, From: cli.User().ID()}
+	resp := response.Response{PartyInvite: []response.PartyInvite{invite}}
+	sendResp := func() { userResponses <- userResponse{Response: resp, UserID: id} }
+	go sendResp()
+	return nil
+}
+
+// handlePartyAcceptRequest handles party-accept request.
+func handlePartyAcceptRequest(cli *Client, id int) error {
+	p := parties[id]
+	if p == nil {
+		return fmt.Errorf("Invite not found: %d", id)
+	}
+	if _, ok := p.Invites[cli.User().ID()]; !ok {
+		return fmt.Errorf("Invite not found: %d", id)
+	}
+	if userParty(cli.User().ID()) != nil {
+		return fmt.Errorf("Already in party")
+	}
+	delete(p.Invites, cli.User().ID())
+	p.Members = append(p.Members, cli.User().ID())
+	return nil
+}
+
+// handlePartyDeclineRequest handles party-decline request.
+func handlePartyDeclineRequest(cli *Client, id int) error {
+	p := parties[id]
+	if p == nil {
+		return fmt.Errorf("Invite not found: %d", id)
+	}
+	if _, ok := p.Invites[cli.User().ID()]; !ok {
+		return fmt.Errorf("Invite not found: %d", id)
+	}
+	p.RemoveInvite(cli.User().ID())
+	return nil
+}
+
+// handlePartyLeaveRequest handles party-leave request.
+func handlePartyLeaveRequest(cli *Client) error {
+	if userParty(cli.User().ID()) == nil {
+		return fmt.Errorf("Not in party")
+	}
+	leaveParty(cli.User())
+	return nil
+}
+
+// handlePartyKickRequest handles party-kick request.
+func handlePartyKickRequest(cli *Client, id string) error {
+	p := userParty(cli.User().ID())
+	if p == nil || p.Leader != cli.User().ID() {
+		return fmt.Errorf("Not a party leader")
+	}
+	if id == cli.User().ID() {
+		return fmt.Errorf("Unable to kick self")
+	}
+	if _, ok := p.Invites[id]; ok {
+		p.RemoveInvite(id)
+		return nil
+	}
+	if !p.Member(id) {
+		return fmt.Errorf("Not a party member: %s", id)
+	}
+	p.Remove(id)
+	return nil
+}
+
+// handlePartyPromoteRequest handles party-promote request.
+func handlePartyPromoteRequest(cli *Client, id string) error {
+	p := userParty(cli.User().ID())
+	if p == nil || p.Leader != cli.User().ID() {
+		return fmt.Errorf("Not a party leader")
+	}
+	if !p.Member(id) {
+		return fmt.Errorf("Not a party member: %s", id)
+	}
+	p.Leader = id
+	return nil
+}
+
+// handlePartyLootRequest handles party-loot request.
+func handlePartyLootRequest(cli *Client, loot string) error {
+	p := userParty(cli.User().ID())
+	if p == nil || p.Leader != cli.User().ID() {
+		return fmt.Errorf("Not a party leader")
+	}
+	switch loot {
+	case request.LootFree, request.LootRoundRobin, request.LootNeedGreed:
+		p.Loot = loot
+		return nil
+	default:
+		return fmt.Errorf("Unknown loot rule: %s", loot)
+	}
+}
+
+// handlePartyRollRequest handles party-roll request.
+func handlePartyRollRequest(cli *Client, req request.PartyRoll) error {
+	roll := partyRolls[req.ID]
+	if roll == nil {
+		return fmt.Errorf("Roll not found: %d", req.ID)
+	}
+	p := parties[roll.PartyID]
+	if p == nil || !p.Member(cli.User().ID()) {
+		return fmt.Errorf("Not a party member")
+	}
+	switch req.Choice {
+	case request.RollNeed, request.RollGreed:
+		if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
+			return fmt.Errorf("Object not controlled: %s %s", req.ObjectID,
+				req.ObjectSerial)
+		}
+	case request.RollPass:
+	default:
+		return fmt.Errorf("Unknown roll choice: %s", req.Choice)
+	}
+	roll.Choices[cli.User().ID()] = req
+	if len(roll.Choices) >= len(p.Members) {
+		resolveRoll(roll)
+	}
+	return nil
+}
+
+// partyLoot distributes specified items from specified loot container
+// according to the loot rule of the client party.
+// Returns false if the items should be transferred to the client character.
+func partyLoot(cli *Client, from item.Container, items map[string][]string) (bool, error) {
+	p := userParty(cli.User().ID())
+	if p == nil {
+		return false, nil
+	}
+	switch p.Loot {
+	case request.LootRoundRobin:
+		return true, roundRobinLoot(p, from, items)
+	case request.LootNeedGreed:
+		return true, startRolls(p, from, items)
+	default:
+		return false, nil
+	}
+}
+
+// roundRobinLoot transfers specified items from specified container
+// to characters of party members, one item to each member in turn.
+// Only members with characters in range of the container receive
+// items. No items are transferred if any of the items can't be
+// transferred.
+func roundRobinLoot(p *party, from item.Container, items map[string][]string) error {
+	tx := newItemsTransaction()
+	next := p.next
+	for id, serials := range items {
+		for _, serial := range serials {
+			char := p.nextMemberChar(from)
+			if char == nil {
+				p.next = next
+				return fmt.Errorf("No party member characters in range")
+			}
+			err := tx.Add(from, char, map[string][]string{id: {serial}})
+			if err != nil {
+				p.next = next
+				return fmt.Errorf("Invalid item: %v", err)
+			}
+		}
+	}
+	err := journalCommit(transferEntry, tx)
+	if err != nil {
+		p.next = next
+		return fmt.Errorf("Unable to transfer items: %v", err)
+	}
+	return nil
+}
+
+// nextMemberChar returns active character of the next party member
+// in turn to receive loot from specified container, or nil if no
+// party member has an active character in range of the container.
+func (p *party) nextMemberChar(from item.Container) *character.Character {
+	for range p.Members {
+		p.next = (p.next + 1) % len(p.Members)
+		usr := data.User(p.Members[p.next])
+		if usr == nil || !usr.Logged {
+			continue
+		}
+		for _, c := range usr.ActiveChars() {
+			char, ok := game.Object(c.ID, c.Serial).(*character.Character)
+			if ok && char.Live() && inRange(from, char) {
+				return char
+			}
+		}
+	}
+	return nil
+}
+
+// startRolls starts party rolls for specified items from specified
+// container.
+func startRolls(p *party, from item.Container, items map[string][]string) error {
+	for id, serials := range items {
+		for _, serial := range serials {
+			if from.Inventory().Item(id, serial) == nil {
+				return fmt.Errorf("Item not found: %s %s", id, serial)
+			}
+			if itemRolled(id, serial) {
+				continue
+			}
+			lastRollID++
+			roll := partyRoll{
+				ID:         lastRollID,
+				PartyID:    p.ID,
+				FromID:     from.ID(),
+				FromSerial: from.Serial(),
+				ItemID:     id,
+				ItemSerial: serial,
+				Choices:    make(map[string]request.PartyRoll),
+				Time:       time.Now(),
+			}
+			partyRolls[roll.ID] = &roll
+			rollResp := response.PartyRoll{
+				ID:         roll.ID,
+				ItemID:     id,
+				ItemSerial: serial,
+			}
+			resp := response.Response{PartyRoll: []response.PartyRoll{rollResp}}
+			p.Notify(resp)
+		}
+	}
+	return nil
+}
+
+// itemRolled checks if there is a pending roll for item with
+// specified ID and serial.
+func itemRolled(id, serial string) bool {
+	for _, r := range partyRolls {
+		if r.ItemID == id && r.ItemSerial == serial {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRoll picks the winner of specified roll and transfers the roll
+// item to the winner character.
+// Members who chose need win over members who chose greed, the winner
+// is picked by the highest random roll.
+func resolveRoll(roll *partyRoll) {
+	delete(partyRolls, roll.ID)
+	p := parties[roll.PartyID]
+	if p == nil {
+		return
+	}
+	rollResp := response.PartyRoll{
+		ID:         roll.ID,
+		ItemID:     roll.ItemID,
+		ItemSerial: roll.ItemSerial,
+	}
+	var winner request.PartyRoll
+	for _, choice := range []string{request.RollNeed, request.RollGreed} {
+		for userID, r := range roll.Choices {
+			if r.Choice != choice {
+				continue
+			}
+			value := rand.Intn(100) + 1
+			if value > rollResp.Roll {
+				rollResp.Roll = value
+				rollResp.Winner = userID
+				winner = r
+			}
+		}
+		if len(rollResp.Winner) > 0 {
+			break
+		}
+	}
+	if len(rollResp.Winner) < 1 {
+		return
+	}
+	from, ok := game.Object(roll.FromID, roll.FromSerial).(item.Container)
+	if !ok {
+		return
+	}
+	to, ok := game.Object(winner.ObjectID, winner.ObjectSerial).(item.Container)
+	if !ok {
+		return
+	}
+	items := map[string][]string{roll.ItemID: {roll.ItemSerial}}
+	err := transferItems(from, to, items)
+	if err != nil {
+		return
+	}
+	resp := response.Response{PartyRoll: []response.PartyRoll{rollResp}}
+	p.Notify(resp)
+}
+
+// expirePartyInvites removes all party invites older than
+// the party invite time.
+func expirePartyInvites() {
+	for _, p := range parties {
+		for id, t := range p.Invites {
+			if time.Since(t) >= partyInviteTime {
+				p.RemoveInvite(id)
+			}
+		}
+	}
+}
+
+// expirePartyRolls resolves all party rolls older than
+// the party roll time.
+func expirePartyRolls() {
+	for _, r := range partyRolls {
+		if time.Since(r.Time) >= partyRollTime {
+			resolveRoll(r)
+		}
+	}
+}
@@ -26,14 +26,12 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/isangeles/flame/area"
 	"github.com/isangeles/flame/character"
 	flamedata "github.com/isangeles/flame/data"
 	"github.com/isangeles/flame/data/res"
 	"github.com/isangeles/flame/dialog"
 	"github.com/isangeles/flame/effect"
 	"github.com/isangeles/flame/item"
-	"github.com/isangeles/flame/objects"
 	"github.com/isangeles/flame/serial"
 	"github.com/isangeles/flame/training"
 	"github.com/isangeles/flame/useaction"
@@ -76,6 +74,23 @@ func handleRequest(req clientRequest) {
 			continue
 		}
 	}
+	if req.ListChars {
+		resp.CharList = handleListCharsRequest(req.Client)
+	}
+	if len(req.SelectChars) > 0 {
+		err := handleSelectCharsRequest(req.Client, req.SelectChars)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle select-chars request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.DeleteChar {
+		err := handleDeleteCharRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle delete-char request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
 	for _, r := range req.SetPos {
 		err := handleSetPosRequest(req.Client, r)
 		if err != nil {
@@ -117,6 +132,16 @@ func handleRequest(req clientRequest) {
 		}
 	}
 	for _, t := range req.Trade {
+		if isMerchantTrade(t) {
+			r, err := handleMerchantTradeRequest(req.Client, t)
+			if err != nil {
+				err := fmt.Sprintf("Unable to handle trade request: %v", err)
+				resp.Error = append(resp.Error, err)
+				continue
+			}
+			resp.TradeCompleted = append(resp.TradeCompleted, r)
+			continue
+		}
 		r, err := handleTradeRequest(req.Client, t)
 		if err != nil {
 			err := fmt.Sprintf("Unable to handle trade request: %v", err)
@@ -188,6 +213,22 @@ func handleRequest(req clientRequest) {
 			resp.Error = append(resp.Error, err)
 		}
 	}
+	for _, r := range req.ChatHistory {
+		records, err := handleChatHistoryRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle chat-history request: %v", err)
+			resp.Error = append(resp.Error, err)
+			continue
+		}
+		resp.ChatHistory = append(resp.ChatHistory, records...)
+	}
+	for _, r := range req.Mute {
+		err := handleMuteRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle mute request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
 	for _, r := range req.Save {
 		err := handleSaveRequest(req.Client, r)
 		if err != nil {
@@ -202,6 +243,24 @@ func handleRequest(req clientRequest) {
 			resp.Error = append(resp.Error, err)
 		}
 	}
+	if req.Saves {
+		r, err := handleSavesRequest(req.Client)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle saves request: %v", err)
+			resp.Error = append(resp.Error, err)
+		} else {
+			resp.Saves = r
+		}
+	}
+	if req.ReloadConfig {
+		r, err := handleReloadConfigRequest(req.Client)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle reload config request: %v", err)
+			resp.Error = append(resp.Error, err)
+		} else {
+			resp.ReloadConfig = r
+		}
+	}
 	for _, c := range req.Command {
 		r, err := handleCommandRequest(req.Client, c)
 		if err != nil {
@@ -214,6 +273,256 @@ func handleRequest(req clientRequest) {
 	for _, a := range req.Accept {
 		handleAcceptRequest(req.Client, a)
 	}
+	for _, d := range req.Decline {
+		handleDeclineRequest(req.Client, d)
+	}
+	for _, c := range req.Cancel {
+		handleCancelRequest(req.Client, c)
+	}
+	for _, ct := range req.CounterTrade {
+		handleCounterTradeRequest(req.Client, ct)
+	}
+	for _, r := range req.MarketSell {
+		err := handleMarketSellRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle market-sell request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.MarketSearch {
+		resp.Market = append(resp.Market, handleMarketSearchRequest(req.Client, r)...)
+	}
+	for _, r := range req.MarketBuy {
+		err := handleMarketBuyRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle market-buy request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.MarketCancel {
+		err := handleMarketCancelRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle market-cancel request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.MarketCollect {
+		err := handleMarketCollectRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle market-collect request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.MailSend {
+		err := handleMailSendRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle mail-send request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if req.MailList {
+		resp.Mail = append(resp.Mail, handleMailListRequest(req.Client)...)
+	}
+	for _, id := range req.MailRead {
+		r, err := handleMailReadRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle mail-read request: %v", err)
+			resp.Error = append(resp.Error, err)
+			continue
+		}
+		resp.Mail = append(resp.Mail, r)
+	}
+	for _, r := range req.MailTake {
+		err := handleMailTakeRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle mail-take request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.MailDelete {
+		err := handleMailDeleteRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle mail-delete request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.PartyInvite {
+		err := handlePartyInviteRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-invite request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.PartyAccept {
+		err := handlePartyAcceptRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-accept request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.PartyDecline {
+		err := handlePartyDeclineRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-decline request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if req.PartyLeave {
+		err := handlePartyLeaveRequest(req.Client)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-leave request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.PartyKick {
+		err := handlePartyKickRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-kick request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if len(req.PartyPromote) > 0 {
+		err := handlePartyPromoteRequest(req.Client, req.PartyPromote)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-promote request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if len(req.PartyLoot) > 0 {
+		err := handlePartyLootRequest(req.Client, req.PartyLoot)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-loot request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.PartyRoll {
+		err := handlePartyRollRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle party-roll request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if len(req.GuildCreate) > 0 {
+		err := handleGuildCreateRequest(req.Client, req.GuildCreate)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-create request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.GuildInvite {
+		err := handleGuildInviteRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-invite request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.GuildAccept {
+		err := handleGuildAcceptRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-accept request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if req.GuildLeave {
+		err := handleGuildLeaveRequest(req.Client)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-leave request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.GuildKick {
+		err := handleGuildKickRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-kick request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.GuildRank {
+		err := handleGuildRankRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-rank request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if len(req.GuildMotd) > 0 {
+		err := handleGuildMotdRequest(req.Client, req.GuildMotd)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-motd request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.GuildDeposit {
+		err := handleGuildDepositRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-deposit request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, r := range req.GuildWithdraw {
+		err := handleGuildWithdrawRequest(req.Client, r)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-withdraw request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.FriendAdd {
+		err := handleFriendAddRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle friend-add request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.FriendRemove {
+		err := handleFriendRemoveRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle friend-remove request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if req.FriendList {
+		resp.Friends = handleFriendListRequest(req.Client)
+	}
+	for _, id := range req.IgnoreAdd {
+		err := handleIgnoreAddRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle ignore-add request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	for _, id := range req.IgnoreRemove {
+		err := handleIgnoreRemoveRequest(req.Client, id)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle ignore-remove request: %v", err)
+			resp.Error = append(resp.Error, err)
+		}
+	}
+	if req.GuildInfo {
+		r, err := handleGuildInfoRequest(req.Client)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle guild-info request: %v", err)
+			resp.Error = append(resp.Error, err)
+		} else {
+			resp.Guild = append(resp.Guild, r)
+		}
+	}
+	for _, s := range req.Scripts {
+		r, err := handleScriptsRequest(req.Client, s)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle scripts request: %v", err)
+			resp.Error = append(resp.Error, err)
+			continue
+		}
+		resp.Scripts = r
+	}
+	if req.Schedule {
+		r, err := handleScheduleRequest(req.Client)
+		if err != nil {
+			err := fmt.Sprintf("Unable to handle schedule request: %v", err)
+			resp.Error = append(resp.Error, err)
+		} else {
+			resp.Schedule = r
+		}
+	}
 	if req.Client.User().Admin {
 		game.pause = req.Pause
 	}
@@ -236,24 +545,42 @@ func handleLoginRequest(cli *Client, req request.Login) error {
 	if user.Logged {
 		return fmt.Errorf("Already logged")
 	}
+	if shutdown.Scheduled() && !user.Admin {
+		return fmt.Errorf("Server is going down")
+	}
 	game.ActivateUserChars(user)
 	cli.SetUser(user)
+	notifyPresence(user, true)
+	game.fireEvent(loginEvent, user.ID())
 	return nil
 }
 
 // handleNewCharRequest handles new character request.
 func handleNewCharRequest(cli *Client, req request.NewChar) error {
-	if !game.ValidNewCharacter(req.Data) {
-		return fmt.Errorf("Invalid character")
+	game.UpdateUserChars(cli.User())
+	err := charsLimitReached(cli.User())
+	if err != nil {
+		return err
+	}
+	err = game.ValidNewCharacter(req)
+	if err != nil {
+		return fmt.Errorf("Invalid character: %v", err)
 	}
-	char := character.New(req.Data)
-	game.Chapter().Resources().Characters = append(game.Chapter().Resources().Characters, req.Data)
-	err := game.SpawnChar(char)
+	charData := newCharData(req.Data)
+	char := character.New(charData)
+	seq, err := journalNewChar(cli.User(), req.Name, char)
 	if err != nil {
+		return fmt.Errorf("Unable to journal char: %v", err)
+	}
+	game.Chapter().Resources().Characters = append(game.Chapter().Resources().Characters, charData)
+	err = game.SpawnChar(char)
+	if err != nil {
+		journalAbort(seq)
 		return fmt.Errorf("Unable to spawn char: %v", err)
 	}
 	game.AddTranslationAll(res.TranslationData{req.Data.ID, []string{req.Name}})
 	cli.User().AddChar(char)
+	game.fireEvent(newCharEvent, cli.User().ID(), scriptTarget(char.ID(), char.Serial()))
 	return nil
 }
 
@@ -272,13 +599,28 @@ func handleSetPosRequest(cli *Client, req request.SetPos) error {
 	if !ok {
 		return fmt.Errorf("Object is not a character: %s %s", req.ID, req.Serial)
 	}
+	// Validate position
+	area := game.Chapter().ObjectArea(char)
+	if area == nil {
+		return fmt.Errorf("Object area not found: %s %s", req.ID, req.Serial)
+	}
+	err := game.validPosition(area, req.PosX, req.PosY)
+	if err != nil {
+		return fmt.Errorf("Invalid position: %v", err)
+	}
 	// Set position
 	char.SetPosition(req.PosX, req.PosY)
+	char.SetDestPoint(req.PosX, req.PosY)
+	game.movement.Reset(char)
+	game.paths.Clear(char)
 	return nil
 }
 
 // handleMoveRequest handles move request.
 func handleMoveRequest(cli *Client, req request.Move) error {
+	if !cli.AllowMove() {
+		return fmt.Errorf("Move requests limit exceeded")
+	}
 	// Retrieve object.
 	chapter := game.Chapter()
 	ob := chapter.AreaObject(req.ID, req.Serial)
@@ -294,7 +636,21 @@ func handleMoveRequest(cli *Client, req request.Move) error {
 	if !ok {
 		return fmt.Errorf("Object is not a character: %s %s", req.ID, req.Serial)
 	}
-	char.SetDestPoint(req.PosX, req.PosY)
+	// Validate destination.
+	area := chapter.ObjectArea(char)
+	if area == nil {
+		return fmt.Errorf("Object area not found: %s %s", req.ID, req.Serial)
+	}
+	err := game.validPosition(area, req.PosX, req.PosY)
+	if err != nil {
+		return fmt.Errorf("Invalid destination: %v", err)
+	}
+	// Set path.
+	path, err := findPath(char, area, req.PosX, req.PosY)
+	if err != nil {
+		return fmt.Errorf("Destination unreachable: %v", err)
+	}
+	game.paths.Set(char, path)
 	return nil
 }
 
@@ -308,7 +664,7 @@ func handleDialogRequest(cli *Client, req request.Dialog) (resp res.ObjectDialog
 	}
 	// Retrieve dialog onwer & target.
 	object := game.Object(req.OwnerID, req.OwnerSerial)
-	if object == nil {
+	if object == nil || ignoredBy(cli.User(), req.OwnerID, req.OwnerSerial) {
 		err = fmt.Errorf("Dialog owner not found: %s %s", req.OwnerID,
 			req.OwnerSerial)
 		return
@@ -491,6 +847,11 @@ func handleTradeRequest(cli *Client, req request.Trade) (resp response.Trade, er
 			req.Sell.ObjectToSerial)
 		return
 	}
+	if ignoredBy(cli.User(), seller.ID(), seller.Serial()) {
+		err = fmt.Errorf("Seller not available: %s %s", req.Sell.ObjectToID,
+			req.Sell.ObjectToSerial)
+		return
+	}
 	object = game.Object(req.Buy.ObjectToID, req.Buy.ObjectToSerial)
 	if object == nil {
 		err = fmt.Errorf("Buyer not found: %s %s", req.Buy.ObjectToID,
@@ -514,21 +875,17 @@ func handleTradeRequest(cli *Client, req request.Trade) (resp response.Trade, er
 			Request: &request.Request{Trade: []request.Trade{req}},
 			Client:  cli,
 		},
-		CharID:     seller.ID(),
-		CharSerial: seller.Serial(),
-		ID:         len(pendingReqs),
-	}
+		CharID:      seller.ID(),
+		CharSerial:  seller.Serial(),
+		OwnerID:     buyer.ID(),
+		OwnerSerial: buyer.Serial(),
+		ID:          lastPendingID + 1,
+		Time:        time.Now(),
+	}
+	lastPendingID = confirmReq.ID
 	addConfirmReq := func() { confirmRequests <- confirmReq }
 	go addConfirmReq()
-	resp = response.Trade{
-		ID:           confirmReq.ID,
-		BuyerID:      req.Buy.ObjectToID,
-		BuyerSerial:  req.Buy.ObjectToSerial,
-		SellerID:     req.Sell.ObjectToID,
-		SellerSerial: req.Sell.ObjectToSerial,
-		ItemsBuy:     req.Buy.Items,
-		ItemsSell:    req.Sell.Items,
-	}
+	resp = tradeResponse(confirmReq.ID, req)
 	return
 }
 
@@ -570,6 +927,15 @@ func handleTransferItemsRequest(cli *Client, req request.TransferItems) error {
 			return fmt.Errorf("Can't transfer items from: %s %s", req.ObjectFromID,
 				req.ObjectFromSerial)
 		}
+		if !cli.User().Controls(from.ID(), from.Serial()) {
+			looted, err := partyLoot(cli, from, req.Items)
+			if err != nil {
+				return fmt.Errorf("Unable to distribute loot: %v", err)
+			}
+			if looted {
+				return nil
+			}
+		}
 		err := transferItems(from, to, req.Items)
 		if err != nil {
 			return fmt.Errorf("Unable to transfer items: %v", err)
@@ -617,6 +983,7 @@ func handleThrowItemsRequest(cli *Client, req request.ThrowItems) error {
 	// Remove items.
 	err := transferItems(char, loot, req.Items)
 	if err != nil {
+		area.RemoveObject(loot)
 		return fmt.Errorf("Unable to remove items: %v", err)
 	}
 	return nil
@@ -802,42 +1169,6 @@ func handleUnequipRequest(cli *Client, req request.Unequip) error {
 	return nil
 }
 
-// handleChatRequest handles chat request.
-func handleChatRequest(cli *Client, req request.Chat) error {
-	// Retrieve object.
-	ob := serial.Object(req.ObjectID, req.ObjectSerial)
-	if ob == nil {
-		return fmt.Errorf("Object not found: %s %s", req.ObjectID,
-			req.ObjectSerial)
-	}
-	if !cli.User().Controls(req.ObjectID, req.ObjectSerial) {
-		return fmt.Errorf("Object is not controled: %s %s", req.ObjectID,
-			req.ObjectSerial)
-	}
-	logger, ok := ob.(objects.Logger)
-	if !ok {
-		return fmt.Errorf("Object is has no chat log: %s %s", req.ObjectID,
-			req.ObjectSerial)
-	}
-	msg := objects.NewMessage(req.Message, req.Translated)
-	logger.ChatLog().Add(msg)
-	areaOb, ok := logger.(area.Object)
-	if !ok {
-		return nil
-	}
-	// Notify near chars.
-	chatResp := response.Chat{
-		ObjectID:     req.ObjectID,
-		ObjectSerial: req.ObjectSerial,
-		Message:      req.Message,
-		Translated:   req.Translated,
-		Time:         msg.Time,
-	}
-	resp := response.Response{Chat: []response.Chat{chatResp}}
-	go game.NotifyNearObjects(areaOb, resp)
-	return nil
-}
-
 // handleTargetRequest handles target request.
 func handleTargetRequest(cli *Client, req request.Target) error {
 	// Retrieve object.
@@ -881,10 +1212,16 @@ func handleSaveRequest(cli *Client, saveName string) error {
 		return fmt.Errorf("You are not an admin")
 	}
 	path := filepath.Join(config.ModulesPath, saveName)
-	err := flamedata.ExportModule(path, game.Data())
+	saveTime := time.Now()
+	seq := journal.Seq()
+	err := flamedata.ExportModule(path, game.Snapshot())
 	if err != nil {
 		return fmt.Errorf("Unable to export module file: %v", err)
 	}
+	err = journal.Compact(saveName, seq, saveTime)
+	if err != nil {
+		log.Printf("Unable to compact journal: %v", err)
+	}
 	return nil
 }
 
@@ -924,7 +1261,33 @@ func handleCommandRequest(cli *Client, cmdText string) (resp response.Command, e
 
 // handleAcceptRequest handles accept request.
 func handleAcceptRequest(cli *Client, id int) {
-	confirm := clientConfirm{id, cli}
+	confirm := clientConfirm{ID: id, Client: cli, Action: confirmAccept}
+	confirmReq := func() { confirmed <- &confirm }
+	go confirmReq()
+}
+
+// handleDeclineRequest handles decline request.
+func handleDeclineRequest(cli *Client, id int) {
+	confirm := clientConfirm{ID: id, Client: cli, Action: confirmDecline}
+	confirmReq := func() { confirmed <- &confirm }
+	go confirmReq()
+}
+
+// handleCancelRequest handles cancel request.
+func handleCancelRequest(cli *Client, id int) {
+	confirm := clientConfirm{ID: id, Client: cli, Action: confirmCancel}
+	confirmReq := func() { confirmed <- &confirm }
+	go confirmReq()
+}
+
+// handleCounterTradeRequest handles counter trade request.
+func handleCounterTradeRequest(cli *Client, req request.CounterTrade) {
+	confirm := clientConfirm{
+		ID:      req.ID,
+		Client:  cli,
+		Action:  confirmCounter,
+		Counter: req,
+	}
 	confirmReq := func() { confirmed <- &confirm }
 	go confirmReq()
 }
@@ -934,9 +1297,6 @@ func handleCloseRequest(cli *Client, timeNano int64) error {
 	if !cli.User().Admin {
 		return fmt.Errorf("You are not an admin")
 	}
-	closeTime := time.Unix(0, timeNano)
-	closeFunc := func() { close = true }
-	log.Printf("Server going down at: %v", closeTime)
-	time.AfterFunc(time.Until(closeTime), closeFunc)
+	shutdown.Schedule(time.Unix(0, timeNano))
 	return nil
 }
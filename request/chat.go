@@ -1,7 +1,7 @@
 /*
  * chat.go
  *
- * Copyright (C) 2020-2021 Dariusz Sikora <dev@isangeles.pl>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -20,10 +20,36 @@
 
 package request
 
+// Chat channels.
+const (
+	ChatSay     = "say"
+	ChatWhisper = "whisper"
+	ChatArea    = "area"
+	ChatParty   = "party"
+	ChatGuild   = "guild"
+	ChatGlobal  = "global"
+	ChatAdmin   = "admin"
+)
+
 // Struct for chat request.
 type Chat struct {
 	ObjectID     string `json:"object-id"`
 	ObjectSerial string `json:"object-serial"`
 	Message      string `json:"message"`
 	Translated   bool   `json:"translated"`
+	Channel      string `json:"channel"`
+	To           string `json:"to"`
+}
+
+// Struct for chat-history request.
+type ChatHistory struct {
+	Channel string `json:"channel"`
+	UserID  string `json:"user-id"`
+	Limit   int    `json:"limit"`
+}
+
+// Struct for mute request.
+type Mute struct {
+	UserID string `json:"user-id"`
+	Time   int64  `json:"time"`
 }
@@ -0,0 +1,43 @@
+/*
+ * party.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package request
+
+// Party loot rules.
+const (
+	LootFree       = "free"
+	LootRoundRobin = "round-robin"
+	LootNeedGreed  = "need-greed"
+)
+
+// Party roll choices.
+const (
+	RollNeed  = "need"
+	RollGreed = "greed"
+	RollPass  = "pass"
+)
+
+// Struct for party-roll request.
+type PartyRoll struct {
+	ID           int    `json:"id"`
+	Choice       string `json:"choice"`
+	ObjectID     string `json:"object-id"`
+	ObjectSerial string `json:"object-serial"`
+}
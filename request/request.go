@@ -1,7 +1,7 @@
 /*
  * request.go
  *
- * Copyright (C) 2020-2025 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -31,6 +31,9 @@ import (
 type Request struct {
 	Login         []Login         `json:"login"`
 	NewChar       []NewChar       `json:"new-char"`
+	ListChars     bool            `json:"list-chars"`
+	SelectChars   []Character     `json:"select-chars"`
+	DeleteChar    []Character     `json:"delete-char"`
 	SetPos        []SetPos        `json:"set-pos"`
 	Move          []Move          `json:"move"`
 	Dialog        []Dialog        `json:"dialog"`
@@ -44,11 +47,53 @@ type Request struct {
 	Unequip       []Unequip       `json:"unequip"`
 	Training      []Training      `json:"training"`
 	Chat          []Chat          `json:"chat"`
+	ChatHistory   []ChatHistory   `json:"chat-history"`
+	Mute          []Mute          `json:"mute"`
 	Target        []Target        `json:"target"`
 	Save          []string        `json:"save"`
 	Load          string          `json:"load"`
+	Saves         bool            `json:"saves"`
+	ReloadConfig  bool            `json:"reload-config"`
 	Command       []string        `json:"command"`
 	Accept        []int           `json:"accept"`
+	Decline       []int           `json:"decline"`
+	Cancel        []int           `json:"cancel"`
+	CounterTrade  []CounterTrade  `json:"counter-trade"`
+	MarketSell    []MarketSell    `json:"market-sell"`
+	MarketSearch  []MarketSearch  `json:"market-search"`
+	MarketBuy     []MarketBuy     `json:"market-buy"`
+	MarketCancel  []int           `json:"market-cancel"`
+	MarketCollect []Character     `json:"market-collect"`
+	MailSend      []MailSend      `json:"mail-send"`
+	MailList      bool            `json:"mail-list"`
+	MailRead      []int           `json:"mail-read"`
+	MailTake      []MailTake      `json:"mail-take"`
+	MailDelete    []int           `json:"mail-delete"`
+	PartyInvite   []string        `json:"party-invite"`
+	PartyAccept   []int           `json:"party-accept"`
+	PartyDecline  []int           `json:"party-decline"`
+	PartyLeave    bool            `json:"party-leave"`
+	PartyKick     []string        `json:"party-kick"`
+	PartyPromote  string          `json:"party-promote"`
+	PartyLoot     string          `json:"party-loot"`
+	PartyRoll     []PartyRoll     `json:"party-roll"`
+	GuildCreate   string          `json:"guild-create"`
+	GuildInvite   []string        `json:"guild-invite"`
+	GuildAccept   []string        `json:"guild-accept"`
+	GuildLeave    bool            `json:"guild-leave"`
+	GuildKick     []string        `json:"guild-kick"`
+	GuildRank     []GuildRank     `json:"guild-rank"`
+	GuildMotd     string          `json:"guild-motd"`
+	GuildDeposit  []GuildBank     `json:"guild-deposit"`
+	GuildWithdraw []GuildBank     `json:"guild-withdraw"`
+	GuildInfo     bool            `json:"guild-info"`
+	FriendAdd     []string        `json:"friend-add"`
+	FriendRemove  []string        `json:"friend-remove"`
+	FriendList    bool            `json:"friend-list"`
+	IgnoreAdd     []string        `json:"ignore-add"`
+	IgnoreRemove  []string        `json:"ignore-remove"`
+	Scripts       []Scripts       `json:"scripts"`
+	Schedule      bool            `json:"schedule"`
 	Close         int64           `json:"close"`
 	Pause         bool            `json:"pause"`
 }
@@ -1,7 +1,7 @@
 /*
  * trade.go
  *
- * Copyright (C) 2020 Dariusz Sikora <dev@isangeles.pl>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -25,3 +25,10 @@ type Trade struct {
 	Sell TransferItems `json:"sell"`
 	Buy  TransferItems `json:"buy"`
 }
+
+// Struct for counter trade request.
+type CounterTrade struct {
+	ID        int                 `json:"id"`
+	ItemsBuy  map[string][]string `json:"items-buy"`
+	ItemsSell map[string][]string `json:"items-sell"`
+}
@@ -1,7 +1,7 @@
 /*
  * request_test.go
  *
- * Copyright (C) 2022-2025 Dariusz Sikora <<ds@isangeles.dev>>
+ * Copyright (C) 2022-2026 Dariusz Sikora <<ds@isangeles.dev>>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -21,14 +21,30 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/isangeles/burn/ash"
 
 	"github.com/isangeles/flame/character"
+	flamedata "github.com/isangeles/flame/data"
 	flameres "github.com/isangeles/flame/data/res"
 	"github.com/isangeles/flame/item"
 	"github.com/isangeles/flame/skill"
 	"github.com/isangeles/flame/training"
 
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
 	"github.com/isangeles/fire/data/res"
 	"github.com/isangeles/fire/request"
 	"github.com/isangeles/fire/user"
@@ -73,6 +89,21 @@ var (
 	userData      = res.UserData{ID: "user"}
 )
 
+// testWorkDir changes the working directory to the temporary
+// test directory, so files saved by the tested functions are
+// removed after the test.
+func testWorkDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unable to get working directory: %v", err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unable to change working directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
 // TestHandleSetPosRequest tests handling of set position request.
 func TestHandleSetPosRequest(t *testing.T) {
 	// Create game & object
@@ -112,6 +143,52 @@ func TestHandleSetPosRequest(t *testing.T) {
 		t.Errorf("Invalid object position: %f != %f, %f != %f",
 			posX, req.PosX, posY, req.PosY)
 	}
+	// Test invalid positions
+	game.areaMaps[area.ID()] = res.AreaMapData{Width: 10, Height: 10, TileWidth: 32, TileHeight: 32}
+	invalid := [][2]float64{{-1, 10}, {10, math.NaN()}, {math.Inf(1), 10}, {320, 10}, {10, 400}}
+	for _, p := range invalid {
+		req.PosX, req.PosY = p[0], p[1]
+		err = handleSetPosRequest(client, req)
+		if err == nil {
+			t.Errorf("Invalid position was not rejected: %f %f", p[0], p[1])
+		}
+	}
+	posX, posY = ob.Position()
+	if posX != 100 || posY != 200 {
+		t.Errorf("Object position changed after invalid requests: %f %f", posX, posY)
+	}
+}
+
+// TestMovementTrackerCheck tests checking of characters movement
+// speed.
+func TestMovementTrackerCheck(t *testing.T) {
+	// Create character
+	char := character.New(charData)
+	char.Attributes().MoveMod = 10
+	chars := func() []*character.Character { return []*character.Character{char} }
+	// Test valid movement
+	mt := newMovementTracker()
+	mt.Reset(char)
+	mt.lastCheck = time.Now().Add(-2 * time.Second)
+	key := char.ID() + char.Serial()
+	pos := mt.positions[key]
+	pos.Time = pos.Time.Add(-time.Second)
+	mt.positions[key] = pos
+	char.SetPosition(pos.X+5, pos.Y)
+	suspects := mt.Check(chars)
+	if len(suspects) > 0 {
+		t.Errorf("Character moving with its own speed was marked as suspicious")
+	}
+	// Test speed hack
+	mt.lastCheck = time.Now().Add(-2 * time.Second)
+	pos = mt.positions[key]
+	pos.Time = pos.Time.Add(-time.Second)
+	mt.positions[key] = pos
+	char.SetPosition(pos.X+100, pos.Y)
+	suspects = mt.Check(chars)
+	if len(suspects) != 1 {
+		t.Errorf("Character exceeding its speed was not marked as suspicious")
+	}
 }
 
 // TestHandleTransferItemsRequest tests handling transfer items request.
@@ -469,3 +546,1806 @@ func TestDialogEndRequest(t *testing.T) {
 		t.Errorf("Dialog between objects not ended")
 	}
 }
+
+// TestHandleListCharsRequest tests handling of list chars request.
+func TestHandleListCharsRequest(t *testing.T) {
+	// Create game & character
+	game = newGame(modData)
+	char := character.New(charData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Create client user
+	user := user.New(userData)
+	user.AddChar(char)
+	client := new(Client)
+	client.SetUser(user)
+	// Test
+	resp := handleListCharsRequest(client)
+	if len(resp) != 1 {
+		t.Fatalf("Invalid number of listed characters: %d != 1", len(resp))
+	}
+	if resp[0].ID != char.ID() || resp[0].Serial != char.Serial() {
+		t.Errorf("Invalid listed character: %s %s != %s %s", resp[0].ID,
+			resp[0].Serial, char.ID(), char.Serial())
+	}
+	if resp[0].Area != area.ID() {
+		t.Errorf("Invalid character area: %s != %s", resp[0].Area, area.ID())
+	}
+	if !resp[0].Active {
+		t.Errorf("Character is not active")
+	}
+}
+
+// TestHandleSelectCharsRequest tests handling of select chars
+// request.
+func TestHandleSelectCharsRequest(t *testing.T) {
+	testWorkDir(t)
+	// Create game & characters
+	game = newGame(modData)
+	char1Data := charData
+	char1Data.ID = "char1"
+	char1 := character.New(char1Data)
+	char2Data := charData
+	char2Data.ID = "char2"
+	char2 := character.New(char2Data)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char1)
+	area.AddObject(char2)
+	// Create client user
+	user := user.New(userData)
+	user.AddChar(char1)
+	user.AddChar(char2)
+	client := new(Client)
+	client.SetUser(user)
+	// Test not owned character
+	req := []request.Character{request.Character{ID: "char3"}}
+	err := handleSelectCharsRequest(client, req)
+	if err == nil {
+		t.Errorf("Selecting not owned character was not rejected")
+	}
+	// Test select
+	req = []request.Character{request.Character{ID: char1.ID(), Serial: char1.Serial()}}
+	err = handleSelectCharsRequest(client, req)
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	if !user.Controls(char1.ID(), char1.Serial()) {
+		t.Errorf("Selected character is not controlled by the user")
+	}
+	if user.Controls(char2.ID(), char2.Serial()) {
+		t.Errorf("Not selected character is controlled by the user")
+	}
+	if !char2.HasFlag(inactiveCharFlag) {
+		t.Errorf("Not selected character was not deactivated")
+	}
+}
+
+// TestHandleDeleteCharRequest tests handling of delete char
+// request.
+func TestHandleDeleteCharRequest(t *testing.T) {
+	testWorkDir(t)
+	// Create game & characters
+	game = newGame(modData)
+	char1Data := charData
+	char1Data.ID = "char1"
+	char1 := character.New(char1Data)
+	char2Data := charData
+	char2Data.ID = "char2"
+	char2 := character.New(char2Data)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char1)
+	area.AddObject(char2)
+	// Create client user
+	user := user.New(userData)
+	user.AddChar(char1)
+	user.AddChar(char2)
+	client := new(Client)
+	client.SetUser(user)
+	req := []request.Character{request.Character{ID: char1.ID(), Serial: char1.Serial()}}
+	err := handleSelectCharsRequest(client, req)
+	if err != nil {
+		t.Fatalf("Select request handling error: %v", err)
+	}
+	// Test not owned character
+	err = handleDeleteCharRequest(client, request.Character{ID: "char3"})
+	if err == nil {
+		t.Errorf("Deleting not owned character was not rejected")
+	}
+	// Test delete
+	err = handleDeleteCharRequest(client, request.Character{ID: char1.ID(), Serial: char1.Serial()})
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	if user.Owns(char1.ID(), char1.Serial()) {
+		t.Errorf("Deleted character is still owned by the user")
+	}
+	if game.Chapter().Character(char1.ID(), char1.Serial()) != nil {
+		t.Errorf("Deleted character is still in the game world")
+	}
+	if user.Controls(char2.ID(), char2.Serial()) {
+		t.Errorf("Not selected character is controlled after deleting the selected one")
+	}
+}
+
+// TestHandleNewCharRequestInvalid tests handling of new character
+// request with invalid character data.
+func TestHandleNewCharRequestInvalid(t *testing.T) {
+	// Create game
+	game = newGame(modData)
+	game.Chapter().Conf().StartLevel = charData.Level
+	game.newCharPolicy.AttrsBudget = 25
+	// Create client user
+	user := user.New(userData)
+	client := new(Client)
+	client.SetUser(user)
+	// Test flags
+	data := charData
+	data.ID = "newChar"
+	data.Flags = []flameres.FlagData{flameres.FlagData{ID: "flag"}}
+	req := request.NewChar{Name: "Name", Data: data}
+	err := handleNewCharRequest(client, req)
+	if err == nil || !strings.Contains(err.Error(), "flags:") {
+		t.Errorf("Character with flags was not rejected by flags rule: %v", err)
+	}
+	// Test level
+	data = charData
+	data.ID = "newChar"
+	data.Level = charData.Level + 1
+	req = request.NewChar{Name: "Name", Data: data}
+	err = handleNewCharRequest(client, req)
+	if err == nil || !strings.Contains(err.Error(), "level:") {
+		t.Errorf("Character with too high level was not rejected by level rule: %v", err)
+	}
+	// Test name
+	game.newCharPolicy.NameMaxLen = 4
+	data = charData
+	data.ID = "newChar"
+	req = request.NewChar{Name: "Żółwi", Data: data}
+	err = handleNewCharRequest(client, req)
+	if err == nil || !strings.Contains(err.Error(), "name:") {
+		t.Errorf("Character with too long name was not rejected by name rule: %v", err)
+	}
+	req.Name = "Żółw"
+	err = validNewCharName(game, req)
+	if err != nil {
+		t.Errorf("Name with multi-byte characters was rejected: %v", err)
+	}
+	game.newCharPolicy.NameMaxLen = 0
+	// Test ID
+	data = charData
+	game.Chapter().Resources().Characters = append(game.Chapter().Resources().Characters,
+		data)
+	req = request.NewChar{Name: "Name", Data: data}
+	err = handleNewCharRequest(client, req)
+	if err == nil || !strings.Contains(err.Error(), "id:") {
+		t.Errorf("Character with taken ID was not rejected by ID rule: %v", err)
+	}
+}
+
+// TestValidNewCharItems tests validation of new character items.
+func TestValidNewCharItems(t *testing.T) {
+	// Create game
+	game = newGame(modData)
+	game.Chapter().Conf().StartItems = []string{itemData.ID}
+	// Create inventory data
+	char := character.New(charData)
+	it := item.NewMisc(itemData)
+	char.Inventory().AddItem(it)
+	data := charData
+	data.ID = "newChar"
+	data.Inventory = char.Data().Inventory
+	if len(data.Inventory.Items) != 1 {
+		t.Fatalf("Invalid number of inventory items: %d != 1",
+			len(data.Inventory.Items))
+	}
+	// Test valid items
+	req := request.NewChar{Name: "Name", Data: data}
+	err := validNewCharItems(game, req)
+	if err != nil {
+		t.Errorf("Valid items were rejected: %v", err)
+	}
+	newData := newCharData(req.Data)
+	if newData.Inventory.Items[0].Serial != "" {
+		t.Errorf("Item serial from the request was not removed: %s",
+			newData.Inventory.Items[0].Serial)
+	}
+	if req.Data.Inventory.Items[0].Serial != it.Serial() {
+		t.Errorf("Request data was modified")
+	}
+	// Test duplicated items
+	dupItem := data.Inventory.Items[0]
+	dupItem.Serial = "dup"
+	req.Data.Inventory.Items = append(req.Data.Inventory.Items, dupItem)
+	err = validNewCharItems(game, req)
+	if err == nil || !strings.Contains(err.Error(), "items:") {
+		t.Errorf("Duplicated items were not rejected by items rule: %v", err)
+	}
+	game.Chapter().Conf().StartItems = []string{itemData.ID, itemData.ID}
+	err = validNewCharItems(game, req)
+	if err != nil {
+		t.Errorf("Items listed twice in start items were rejected: %v", err)
+	}
+}
+
+// TestTransferItemsMissingItem tests if no items are transferred
+// when one of the requested items is missing.
+func TestTransferItemsMissingItem(t *testing.T) {
+	// Create characters & items
+	charFromData := charData
+	charFromData.ID = "charFrom"
+	charFrom := character.New(charFromData)
+	charToData := charData
+	charToData.ID = "charTo"
+	charTo := character.New(charToData)
+	item1 := item.NewMisc(itemData)
+	charFrom.Inventory().AddItem(item1)
+	// Test
+	items := make(map[string][]string)
+	items[item1.ID()] = []string{item1.Serial(), "missing"}
+	err := transferItems(charFrom, charTo, items)
+	if err == nil {
+		t.Fatalf("No error for missing item")
+	}
+	if charFrom.Inventory().Item(item1.ID(), item1.Serial()) == nil {
+		t.Errorf("Item should stay in %s inventory: %s %s", charFrom.ID(),
+			item1.ID(), item1.Serial())
+	}
+	if charTo.Inventory().Item(item1.ID(), item1.Serial()) != nil {
+		t.Errorf("Item should not be added to %s inventory: %s %s", charTo.ID(),
+			item1.ID(), item1.Serial())
+	}
+}
+
+// testPendingTrade creates pending trade request between two
+// characters controlled by separate clients.
+func testPendingTrade(t *testing.T) (ownerClient, charClient *Client, req charConfirmRequest) {
+	ownerData := charData
+	ownerData.ID = "owner"
+	owner := character.New(ownerData)
+	buyerData := charData
+	buyerData.ID = "buyer"
+	buyer := character.New(buyerData)
+	ownerUser := user.New(res.UserData{ID: "ownerUser"})
+	ownerUser.AddChar(owner)
+	ownerClient = new(Client)
+	ownerClient.SetUser(ownerUser)
+	charUser := user.New(res.UserData{ID: "charUser"})
+	charUser.AddChar(buyer)
+	charClient = new(Client)
+	charClient.SetUser(charUser)
+	trade := request.Trade{
+		Buy: request.TransferItems{
+			ObjectToID:     owner.ID(),
+			ObjectToSerial: owner.Serial(),
+			Items:          map[string][]string{"item": {"1"}},
+		},
+		Sell: request.TransferItems{
+			ObjectToID:     buyer.ID(),
+			ObjectToSerial: buyer.Serial(),
+			Items:          map[string][]string{"item": {"2"}},
+		},
+	}
+	req = charConfirmRequest{
+		clientRequest: clientRequest{&request.Request{Trade: []request.Trade{trade}}, ownerClient},
+		CharID:        buyer.ID(),
+		CharSerial:    buyer.Serial(),
+		OwnerID:       owner.ID(),
+		OwnerSerial:   owner.Serial(),
+		ID:            1,
+		Time:          time.Now(),
+	}
+	pendingReqs[req.ID] = req
+	t.Cleanup(func() { delete(pendingReqs, req.ID) })
+	return
+}
+
+// TestHandleConfirmDecline tests declining pending trade request.
+func TestHandleConfirmDecline(t *testing.T) {
+	ownerClient, charClient, req := testPendingTrade(t)
+	// Test decline by the request owner.
+	handleConfirm(&clientConfirm{ID: req.ID, Client: ownerClient, Action: confirmDecline})
+	if _, ok := pendingReqs[req.ID]; !ok {
+		t.Fatalf("Pending request declined by the request owner")
+	}
+	// Test decline by the confirming character.
+	handleConfirm(&clientConfirm{ID: req.ID, Client: charClient, Action: confirmDecline})
+	if _, ok := pendingReqs[req.ID]; ok {
+		t.Errorf("Declined request was not removed")
+	}
+}
+
+// TestHandleConfirmCancel tests canceling pending trade request.
+func TestHandleConfirmCancel(t *testing.T) {
+	ownerClient, charClient, req := testPendingTrade(t)
+	// Test cancel by the confirming character.
+	handleConfirm(&clientConfirm{ID: req.ID, Client: charClient, Action: confirmCancel})
+	if _, ok := pendingReqs[req.ID]; !ok {
+		t.Fatalf("Pending request canceled by the confirming character")
+	}
+	// Test cancel by the request owner.
+	handleConfirm(&clientConfirm{ID: req.ID, Client: ownerClient, Action: confirmCancel})
+	if _, ok := pendingReqs[req.ID]; ok {
+		t.Errorf("Canceled request was not removed")
+	}
+}
+
+// TestHandleConfirmCounter tests countering pending trade request.
+func TestHandleConfirmCounter(t *testing.T) {
+	_, charClient, req := testPendingTrade(t)
+	counter := request.CounterTrade{
+		ID:        req.ID,
+		ItemsBuy:  map[string][]string{"item": {"3"}},
+		ItemsSell: map[string][]string{"item": {"4"}},
+	}
+	handleConfirm(&clientConfirm{ID: req.ID, Client: charClient, Action: confirmCounter,
+		Counter: counter})
+	countered, ok := pendingReqs[req.ID]
+	if !ok {
+		t.Fatalf("Countered request was removed")
+	}
+	if countered.OwnerID != req.CharID || countered.CharID != req.OwnerID {
+		t.Errorf("Request owner and confirming character were not swapped: %s %s",
+			countered.OwnerID, countered.CharID)
+	}
+	if countered.Client != charClient {
+		t.Errorf("Countered request owner client was not changed")
+	}
+	trade := countered.Trade[0]
+	if trade.Buy.Items["item"][0] != "3" || trade.Sell.Items["item"][0] != "4" {
+		t.Errorf("Trade items were not countered: %v %v", trade.Buy.Items,
+			trade.Sell.Items)
+	}
+}
+
+// TestExpirePendingReqs tests expiring pending trade requests.
+func TestExpirePendingReqs(t *testing.T) {
+	_, _, req := testPendingTrade(t)
+	expirePendingReqs()
+	if _, ok := pendingReqs[req.ID]; !ok {
+		t.Fatalf("Pending request expired before the trade timeout")
+	}
+	req.Time = time.Now().Add(-time.Duration(config.TradeTimeout+1) * time.Millisecond)
+	pendingReqs[req.ID] = req
+	expirePendingReqs()
+	if _, ok := pendingReqs[req.ID]; ok {
+		t.Errorf("Pending request was not expired")
+	}
+}
+
+// TestItemsValue tests calculating value of items.
+func TestItemsValue(t *testing.T) {
+	char := character.New(charData)
+	item1 := item.NewMisc(flameres.MiscItemData{ID: "item", Value: 10})
+	item2 := item.NewMisc(flameres.MiscItemData{ID: "item", Value: 5})
+	char.Inventory().AddItem(item1)
+	char.Inventory().AddItem(item2)
+	items := map[string][]string{"item": {item1.Serial(), item2.Serial()}}
+	value, err := itemsValue(char, items, 1.5)
+	if err != nil {
+		t.Fatalf("Unable to calculate items value: %v", err)
+	}
+	if value != 23 {
+		t.Errorf("Invalid items value: %d != 23", value)
+	}
+	items["item"] = append(items["item"], "missing")
+	_, err = itemsValue(char, items, 1)
+	if err == nil {
+		t.Errorf("No error for missing item")
+	}
+}
+
+// TestMerchantsAccepts tests merchant trade acceptance.
+func TestMerchantsAccepts(t *testing.T) {
+	merchant := res.MerchantData{ID: "merchant", SellMod: 2, BuyMod: 1, StockSize: 1}
+	merchants := newMerchants(merchant)
+	sellerData := charData
+	sellerData.ID = "seller"
+	seller := character.New(sellerData)
+	buyerData := charData
+	buyerData.ID = "buyer"
+	buyer := character.New(buyerData)
+	sword := item.NewMisc(flameres.MiscItemData{ID: "sword", Value: 10})
+	seller.Inventory().AddItem(sword)
+	gem1 := item.NewMisc(flameres.MiscItemData{ID: "gem", Value: 10})
+	gem2 := item.NewMisc(flameres.MiscItemData{ID: "gem", Value: 10})
+	buyer.Inventory().AddItem(gem1)
+	buyer.Inventory().AddItem(gem2)
+	req := request.Trade{
+		Buy:  request.TransferItems{Items: map[string][]string{"sword": {sword.Serial()}}},
+		Sell: request.TransferItems{Items: map[string][]string{"gem": {gem1.Serial()}}},
+	}
+	// Test insufficient value.
+	err := merchants.Accepts(&merchant, seller, buyer, req)
+	if err == nil {
+		t.Errorf("Trade with insufficient value was accepted")
+	}
+	// Test stock size.
+	req.Sell.Items["gem"] = append(req.Sell.Items["gem"], gem2.Serial())
+	err = merchants.Accepts(&merchant, seller, buyer, req)
+	if err == nil {
+		t.Errorf("Trade exceeding merchant stock size was accepted")
+	}
+	// Test valid trade.
+	merchant.StockSize = 2
+	err = merchants.Accepts(&merchant, seller, buyer, req)
+	if err != nil {
+		t.Errorf("Valid trade was rejected: %v", err)
+	}
+}
+
+// TestHandleMarketSearchRequest tests handling market-search request.
+func TestHandleMarketSearchRequest(t *testing.T) {
+	// Create marketplace
+	marketData := res.MarketData{
+		Listings: []res.MarketListingData{
+			{ID: 1, ItemID: "sword", Price: 10},
+			{ID: 2, ItemID: "shortSword", Price: 5},
+			{ID: 3, ItemID: "shield", Price: 5},
+		},
+	}
+	marketplace = newMarket(marketData)
+	client := new(Client)
+	// Test
+	req := request.MarketSearch{ItemID: "word", MaxPrice: 5}
+	listings := handleMarketSearchRequest(client, req)
+	if len(listings) != 1 {
+		t.Fatalf("Invalid number of listings: %d != 1", len(listings))
+	}
+	if listings[0].ID != 2 {
+		t.Errorf("Invalid listing: %d != 2", listings[0].ID)
+	}
+}
+
+// TestMarketCollect tests collecting of marketplace returns.
+func TestMarketCollect(t *testing.T) {
+	testWorkDir(t)
+	journal = newGameJournal("journal")
+	t.Cleanup(func() { journal = newGameJournal("") })
+	// Create character and return.
+	char := character.New(charData)
+	it := item.NewMisc(itemData)
+	escrow.Hold(it)
+	ret := res.MarketReturnData{ItemID: it.ID(), ItemSerial: it.Serial()}
+	// Test
+	err := collect(char, ret)
+	if err != nil {
+		t.Fatalf("Unable to collect return: %v", err)
+	}
+	if char.Inventory().Item(it.ID(), it.Serial()) == nil {
+		t.Errorf("Item should be added to the character inventory")
+	}
+	if escrow.items[it.ID()+it.Serial()] != nil {
+		t.Errorf("Item should be released from the escrow")
+	}
+	entries, err := data.LoadJournal("journal")
+	if err != nil {
+		t.Fatalf("Unable to load journal: %v", err)
+	}
+	if len(entries) != 1 || len(entries[0].Transfers) != 1 {
+		t.Fatalf("Invalid journal entries: %v", entries)
+	}
+	transfer := entries[0].Transfers[0]
+	if len(transfer.FromID) > 0 || transfer.ToID != char.ID() {
+		t.Errorf("Invalid journal transfer: %v", transfer)
+	}
+}
+
+// TestHandleMailListRequest tests handling mail-list request.
+func TestHandleMailListRequest(t *testing.T) {
+	// Create user
+	data := userData
+	data.Mail = []res.MailData{{ID: 1, From: "user2", Subject: "Subject", Text: "Text"}}
+	user := user.New(data)
+	client := new(Client)
+	client.SetUser(user)
+	// Test
+	mail := handleMailListRequest(client)
+	if len(mail) != 1 {
+		t.Fatalf("Invalid number of mail: %d != 1", len(mail))
+	}
+	if mail[0].Subject != "Subject" {
+		t.Errorf("Invalid mail subject: %s != Subject", mail[0].Subject)
+	}
+	if len(mail[0].Text) > 0 {
+		t.Errorf("Mail text should not be listed: %s", mail[0].Text)
+	}
+}
+
+// TestHandleMailSendRequest tests handling mail-send request
+// with mail limits and ignoring recipient.
+func TestHandleMailSendRequest(t *testing.T) {
+	testLoadUsers(t, res.UserData{ID: "mailSender"}, res.UserData{ID: "mailRecipient"},
+		res.UserData{ID: "mailIgnoring", Ignored: []string{"mailSender"}})
+	mailMaxLen, mailboxSize := config.MailMaxLen, config.MailboxSize
+	config.MailMaxLen, config.MailboxSize = 5, 1
+	defer func() { config.MailMaxLen, config.MailboxSize = mailMaxLen, mailboxSize }()
+	client := new(Client)
+	client.SetUser(data.User("mailSender"))
+	// Test.
+	err := handleMailSendRequest(client, request.MailSend{To: "mailIgnoring", Text: "Text"})
+	if err == nil {
+		t.Errorf("Mail to ignoring recipient was not rejected")
+	}
+	err = handleMailSendRequest(client, request.MailSend{To: "mailRecipient", Subject: "Subject"})
+	if err == nil {
+		t.Errorf("Mail with too long subject was not rejected")
+	}
+	err = handleMailSendRequest(client, request.MailSend{To: "mailRecipient", Text: "Long text"})
+	if err == nil {
+		t.Errorf("Mail with too long text was not rejected")
+	}
+	err = handleMailSendRequest(client, request.MailSend{To: "mailRecipient", Text: "Text"})
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	err = handleMailSendRequest(client, request.MailSend{To: "mailRecipient", Text: "Text"})
+	if err == nil {
+		t.Errorf("Mail to full mailbox was not rejected")
+	}
+	if len(data.User("mailIgnoring").Mail()) > 0 {
+		t.Errorf("Mail was delivered to ignoring recipient")
+	}
+	if len(data.User("mailRecipient").Mail()) != 1 {
+		t.Errorf("Invalid number of recipient mail: %d != 1",
+			len(data.User("mailRecipient").Mail()))
+	}
+}
+
+// TestHandleChatRequestAdmin tests handling chat request
+// on the admin channel.
+func TestHandleChatRequestAdmin(t *testing.T) {
+	// Create user & client.
+	user := user.New(userData)
+	client := new(Client)
+	client.SetUser(user)
+	// Test.
+	req := request.Chat{Message: "Test message", Channel: request.ChatAdmin}
+	err := handleChatRequest(client, req)
+	if err == nil {
+		t.Errorf("Admin message from non-admin user was not rejected")
+	}
+}
+
+// TestChatLogCompact tests truncating the chat history file.
+func TestChatLogCompact(t *testing.T) {
+	testWorkDir(t)
+	historySize := config.ChatHistorySize
+	config.ChatHistorySize = 2
+	defer func() { config.ChatHistorySize = historySize }()
+	history := newChatLog("chat-history")
+	for i := 0; i < 5; i++ {
+		history.Add(res.ChatRecordData{Message: fmt.Sprintf("%d", i)})
+	}
+	records, err := data.LoadChatHistory("chat-history", 0)
+	if err != nil {
+		t.Fatalf("Unable to load chat history: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Invalid number of saved records: %d != 2", len(records))
+	}
+	if records[0].Message != "3" || records[1].Message != "4" {
+		t.Errorf("Invalid saved records: %v", records)
+	}
+}
+
+// TestHandleChatRequestFilter tests filtering chat messages.
+func TestHandleChatRequestFilter(t *testing.T) {
+	// Create game & character.
+	game = newGame(modData)
+	char := character.New(charData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Create user & client.
+	user := user.New(userData)
+	user.AddChar(char)
+	client := new(Client)
+	client.SetUser(user)
+	// Create filter & request.
+	chatFilter = []*regexp.Regexp{regexp.MustCompile("(?i)bad")}
+	defer func() { chatFilter = nil }()
+	req := request.Chat{
+		ObjectID:     char.ID(),
+		ObjectSerial: char.Serial(),
+		Message:      "Bad message, badminton is not bad",
+	}
+	// Test.
+	err := handleChatRequest(client, req)
+	if err != nil {
+		t.Fatalf("Request handing error: %v", err)
+	}
+	msg := char.ChatLog().Messages()[0]
+	if msg.Text != "*** message, badminton is not ***" {
+		t.Errorf("Message was not filtered: %s", msg.Text)
+	}
+}
+
+// TestHandlePartyLootRequest tests handling party-loot request.
+func TestHandlePartyLootRequest(t *testing.T) {
+	// Create users & party.
+	leader := user.New(res.UserData{ID: "leader"})
+	member := user.New(res.UserData{ID: "member"})
+	parties[1] = &party{
+		ID:      1,
+		Leader:  leader.ID(),
+		Members: []string{leader.ID(), member.ID()},
+		Invites: make(map[string]time.Time),
+		Loot:    request.LootFree,
+	}
+	defer delete(parties, 1)
+	leaderClient := new(Client)
+	leaderClient.SetUser(leader)
+	memberClient := new(Client)
+	memberClient.SetUser(member)
+	// Test.
+	err := handlePartyLootRequest(memberClient, request.LootNeedGreed)
+	if err == nil {
+		t.Errorf("Loot rule change from non-leader member was not rejected")
+	}
+	err = handlePartyLootRequest(leaderClient, "invalid")
+	if err == nil {
+		t.Errorf("Invalid loot rule was not rejected")
+	}
+	err = handlePartyLootRequest(leaderClient, request.LootNeedGreed)
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	if parties[1].Loot != request.LootNeedGreed {
+		t.Errorf("Invalid party loot rule: %s != %s", parties[1].Loot,
+			request.LootNeedGreed)
+	}
+}
+
+// TestHandlePartyDeclineRequest tests handling party-decline request.
+func TestHandlePartyDeclineRequest(t *testing.T) {
+	// Create users & party.
+	leader := user.New(res.UserData{ID: "leader"})
+	invited := user.New(res.UserData{ID: "invited"})
+	parties[1] = &party{
+		ID:      1,
+		Leader:  leader.ID(),
+		Members: []string{leader.ID()},
+		Invites: map[string]time.Time{invited.ID(): time.Now()},
+		Loot:    request.LootFree,
+	}
+	defer delete(parties, 1)
+	leaderClient := new(Client)
+	leaderClient.SetUser(leader)
+	invitedClient := new(Client)
+	invitedClient.SetUser(invited)
+	// Test.
+	err := handlePartyDeclineRequest(leaderClient, 1)
+	if err == nil {
+		t.Errorf("Decline without invite was not rejected")
+	}
+	err = handlePartyDeclineRequest(invitedClient, 1)
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	if parties[1] != nil {
+		t.Errorf("Party without members and invites was not disbanded")
+	}
+}
+
+// TestHandlePartyInviteRequestFull tests if rejected party invite
+// does not leave a new party behind.
+func TestHandlePartyInviteRequestFull(t *testing.T) {
+	testLoadUsers(t, res.UserData{ID: "leader"}, res.UserData{ID: "invited"})
+	data.User("invited").Logged = true
+	defer func() { data.User("invited").Logged = false }()
+	partyMaxSize := config.PartyMaxSize
+	config.PartyMaxSize = 1
+	defer func() { config.PartyMaxSize = partyMaxSize }()
+	client := new(Client)
+	client.SetUser(data.User("leader"))
+	// Test.
+	err := handlePartyInviteRequest(client, "invited")
+	if err == nil {
+		t.Errorf("Invite to full party was not rejected")
+	}
+	if p := userParty("leader"); p != nil {
+		t.Errorf("Party was created for rejected invite: %d", p.ID)
+		delete(parties, p.ID)
+	}
+}
+
+// TestExpirePartyInvites tests expiring party invites.
+func TestExpirePartyInvites(t *testing.T) {
+	parties[1] = &party{
+		ID:      1,
+		Leader:  "leader",
+		Members: []string{"leader", "member"},
+		Invites: map[string]time.Time{
+			"invited": time.Now(),
+			"expired": time.Now().Add(-partyInviteTime),
+		},
+	}
+	defer delete(parties, 1)
+	expirePartyInvites()
+	if _, ok := parties[1].Invites["expired"]; ok {
+		t.Errorf("Expired invite was not removed")
+	}
+	if _, ok := parties[1].Invites["invited"]; !ok {
+		t.Errorf("Pending invite was removed")
+	}
+}
+
+// TestPartyResponse tests if party response is not modified
+// by changes of the party members.
+func TestPartyResponse(t *testing.T) {
+	p := party{ID: 1, Members: []string{"user1", "user2", "user3"}}
+	parties[p.ID] = &p
+	defer delete(parties, p.ID)
+	resp := p.Response()
+	p.Remove("user1")
+	if resp.Members[0] != "user1" || resp.Members[1] != "user2" ||
+		resp.Members[2] != "user3" {
+		t.Errorf("Party response members were modified: %v", resp.Members)
+	}
+}
+
+// testLoadUsers saves specified users data to the users directory in
+// the test working directory and loads them as server users.
+func testLoadUsers(t *testing.T, users ...res.UserData) {
+	testWorkDir(t)
+	for _, d := range users {
+		err := data.SaveUser("users", user.New(d))
+		if err != nil {
+			t.Fatalf("Unable to save user: %v", err)
+		}
+	}
+	err := data.LoadUsers("users")
+	if err != nil {
+		t.Fatalf("Unable to load users: %v", err)
+	}
+}
+
+// TestHandleFriendAddRequest tests handling friend-add and
+// ignore-add requests.
+func TestHandleFriendAddRequest(t *testing.T) {
+	testLoadUsers(t, res.UserData{ID: "friendUser"}, res.UserData{ID: "friend"})
+	usr := data.User("friendUser")
+	client := new(Client)
+	client.SetUser(usr)
+	// Test.
+	err := handleFriendAddRequest(client, "missing")
+	if err == nil {
+		t.Errorf("Friend-add request with unknown user was not rejected")
+	}
+	err = handleFriendAddRequest(client, usr.ID())
+	if err == nil {
+		t.Errorf("Friend-add request with client user was not rejected")
+	}
+	err = handleFriendAddRequest(client, "friend")
+	if err != nil {
+		t.Fatalf("Friend-add request handling error: %v", err)
+	}
+	if !usr.Friend("friend") {
+		t.Errorf("User was not added to friends")
+	}
+	err = handleIgnoreAddRequest(client, "friend")
+	if err != nil {
+		t.Fatalf("Ignore-add request handling error: %v", err)
+	}
+	if !usr.Ignores("friend") {
+		t.Errorf("User was not ignored")
+	}
+	err = handleIgnoreRemoveRequest(client, "friend")
+	if err != nil {
+		t.Fatalf("Ignore-remove request handling error: %v", err)
+	}
+	if usr.Ignores("friend") {
+		t.Errorf("User is still ignored")
+	}
+}
+
+// TestHandleFriendListRequest tests handling friend-list request.
+func TestHandleFriendListRequest(t *testing.T) {
+	testLoadUsers(t, res.UserData{ID: "listUser", Friends: []string{"online", "ignoring"}},
+		res.UserData{ID: "online"}, res.UserData{ID: "ignoring", Ignored: []string{"listUser"}})
+	data.User("online").Logged = true
+	data.User("ignoring").Logged = true
+	defer func() {
+		data.User("online").Logged = false
+		data.User("ignoring").Logged = false
+	}()
+	client := new(Client)
+	client.SetUser(data.User("listUser"))
+	// Test.
+	for _, f := range handleFriendListRequest(client) {
+		switch f.UserID {
+		case "online":
+			if !f.Online {
+				t.Errorf("Logged friend is shown as offline")
+			}
+		case "ignoring":
+			if f.Online {
+				t.Errorf("Friend that ignores the user is shown as online")
+			}
+		}
+	}
+}
+
+// TestNotifyPresence tests sending presence responses.
+func TestNotifyPresence(t *testing.T) {
+	testLoadUsers(t, res.UserData{ID: "presenceUser", Ignored: []string{"ignored"}},
+		res.UserData{ID: "friend", Friends: []string{"presenceUser"}},
+		res.UserData{ID: "ignored", Friends: []string{"presenceUser"}})
+	data.User("friend").Logged = true
+	data.User("ignored").Logged = true
+	defer func() {
+		data.User("friend").Logged = false
+		data.User("ignored").Logged = false
+	}()
+	// Test.
+	notifyPresence(data.User("presenceUser"), true)
+	notified := make(map[string]bool)
+	timeout := time.After(100 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case resp := <-userResponses:
+			for _, p := range resp.Response.Presence {
+				if p.UserID == "presenceUser" && p.Online {
+					notified[resp.UserID] = true
+				}
+			}
+		case <-timeout:
+			done = true
+		}
+	}
+	if !notified["friend"] {
+		t.Errorf("Friend was not notified")
+	}
+	if notified["ignored"] {
+		t.Errorf("Ignored user was notified")
+	}
+}
+
+// TestTakeItem tests moving of mail items from the escrow.
+func TestTakeItem(t *testing.T) {
+	testWorkDir(t)
+	journal = newGameJournal("journal")
+	t.Cleanup(func() { journal = newGameJournal("") })
+	// Create character and item.
+	char := character.New(charData)
+	it := item.NewMisc(itemData)
+	escrow.Hold(it)
+	// Test
+	err := takeItem(char, it.ID(), it.Serial())
+	if err != nil {
+		t.Fatalf("Unable to take item: %v", err)
+	}
+	if char.Inventory().Item(it.ID(), it.Serial()) == nil {
+		t.Errorf("Item should be added to the character inventory")
+	}
+	if escrow.items[it.ID()+it.Serial()] != nil {
+		t.Errorf("Item should be released from the escrow")
+	}
+	entries, err := data.LoadJournal("journal")
+	if err != nil {
+		t.Fatalf("Unable to load journal: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Invalid number of journal entries: %d != 1", len(entries))
+	}
+}
+
+// TestHandleGuildKickRequest tests handling guild-kick request.
+func TestHandleGuildKickRequest(t *testing.T) {
+	// Create users & guild.
+	leader := user.New(res.UserData{ID: "leader"})
+	member := user.New(res.UserData{ID: "member"})
+	guildData := res.GuildData{
+		ID:    "guild",
+		Ranks: defaultGuildRanks(),
+		Members: []res.GuildMemberData{
+			{UserID: leader.ID(), Rank: "leader"},
+			{UserID: member.ID(), Rank: "member"},
+		},
+	}
+	guilds[guildData.ID] = newGuild(guildData)
+	defer delete(guilds, guildData.ID)
+	client := new(Client)
+	client.SetUser(member)
+	// Test.
+	err := handleGuildKickRequest(client, leader.ID())
+	if err == nil {
+		t.Errorf("Kick request without permission was not rejected")
+	}
+	guilds[guildData.ID].Members[1].Rank = "officer"
+	err = handleGuildKickRequest(client, leader.ID())
+	if err == nil {
+		t.Errorf("Kick request for member with higher rank was not rejected")
+	}
+	if guilds[guildData.ID].Member(leader.ID()) == nil {
+		t.Errorf("Leader was removed from the guild")
+	}
+}
+
+// TestHandleGuildWithdrawRequest tests handling guild-withdraw request.
+func TestHandleGuildWithdrawRequest(t *testing.T) {
+	testWorkDir(t)
+	// Create game & character.
+	game = newGame(modData)
+	char := character.New(charData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Create user & guild.
+	member := user.New(res.UserData{ID: "member"})
+	member.AddChar(char)
+	it := item.NewMisc(itemData)
+	escrow.Hold(it)
+	guildData := res.GuildData{
+		ID:      "guild",
+		Ranks:   defaultGuildRanks(),
+		Members: []res.GuildMemberData{{UserID: member.ID(), Rank: "member"}},
+		Bank:    []res.GuildItemData{{ID: it.ID(), Serial: it.Serial()}},
+	}
+	guilds[guildData.ID] = newGuild(guildData)
+	defer delete(guilds, guildData.ID)
+	client := new(Client)
+	client.SetUser(member)
+	// Test.
+	req := request.GuildBank{
+		ObjectID:     char.ID(),
+		ObjectSerial: char.Serial(),
+		Items:        map[string][]string{it.ID(): {it.Serial(), "missing"}},
+	}
+	err := handleGuildWithdrawRequest(client, req)
+	if err == nil {
+		t.Errorf("Withdraw request with missing item was not rejected")
+	}
+	if char.Inventory().Item(it.ID(), it.Serial()) != nil {
+		t.Errorf("Item was added from the rejected request")
+	}
+	req.Items = map[string][]string{it.ID(): {it.Serial()}}
+	err = handleGuildWithdrawRequest(client, req)
+	if err != nil {
+		t.Fatalf("Unable to withdraw item: %v", err)
+	}
+	if char.Inventory().Item(it.ID(), it.Serial()) == nil {
+		t.Errorf("Item was not added to the character inventory")
+	}
+	if len(guilds[guildData.ID].Bank) > 0 {
+		t.Errorf("Item was not removed from the guild bank")
+	}
+	if guilds[guildData.ID].Member(member.ID()).Withdrawn != 1 {
+		t.Errorf("Invalid number of withdrawn items: %d != 1",
+			guilds[guildData.ID].Member(member.ID()).Withdrawn)
+	}
+}
+
+// TestServerEventsCheck tests triggering server events for
+// changes of characters state.
+func TestServerEventsCheck(t *testing.T) {
+	// Create game & character.
+	game = newGame(modData)
+	char := character.New(charData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Register event script.
+	path := filepath.Join(game.Conf().Path, config.ModuleServerPath, "events",
+		charDeathEvent, "test.ash")
+	scripts := map[string]map[string]string{
+		charDeathEvent: {path: "true { wait(1000); };"},
+	}
+	game.events.SetScripts(scripts)
+	// Test, with separate events tracker to avoid checks from
+	// the game update loop.
+	events := newServerEvents()
+	chars := []*character.Character{char}
+	events.Check(game, chars)
+	if events.areas[char.ID()+char.Serial()] != area.ID() {
+		t.Errorf("Character area was not tracked")
+	}
+	char.SetHealth(0)
+	events.Check(game, chars)
+	name := game.scriptName(path)
+	started := false
+	for i := 0; i < 10 && !started; i++ {
+		started = len(game.scripts.Named(name)) > 0
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !started {
+		t.Errorf("Event script was not started")
+	}
+	game.StopScript(name)
+	events.ResetAreas()
+	if len(events.areas) > 0 || len(events.occupied) > 0 {
+		t.Errorf("Tracked areas were not reset")
+	}
+}
+
+// TestAreaScripts tests starting and stopping area scripts.
+func TestAreaScripts(t *testing.T) {
+	testWorkDir(t)
+	// Create game & character.
+	game = newGame(modData)
+	char := character.New(charData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Create area script file.
+	err := os.MkdirAll(game.areaScriptsPath(area.ID()), 0755)
+	if err != nil {
+		t.Fatalf("Unable to create scripts dir: %v", err)
+	}
+	path := filepath.Join(game.areaScriptsPath(area.ID()), "test.ash")
+	err = os.WriteFile(path, []byte("true { wait(1000); };"), 0644)
+	if err != nil {
+		t.Fatalf("Unable to create script file: %v", err)
+	}
+	name := game.scriptName(path)
+	defer game.StopScript(name)
+	// Test, with separate events tracker to avoid checks from
+	// the game update loop.
+	events := newServerEvents()
+	events.Check(game, []*character.Character{char})
+	started := false
+	for i := 0; i < 10 && !started; i++ {
+		started = len(game.scripts.Named(name)) > 0
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !started {
+		t.Fatalf("Area script was not started")
+	}
+	scripts := game.scripts.Named(name)
+	events.Check(game, nil)
+	for _, s := range scripts {
+		if !s.Stopped() {
+			t.Errorf("Area script was not stopped")
+		}
+	}
+}
+
+// TestHandleScriptsRequest tests handling scripts request.
+func TestHandleScriptsRequest(t *testing.T) {
+	// Create game & script.
+	game = newGame(modData)
+	script, err := newGameScript("test.ash", "", "true { wait(1000); };", time.Now())
+	if err != nil {
+		t.Fatalf("Unable to create script: %v", err)
+	}
+	game.scripts.Add(script)
+	// Create user & client.
+	user := user.New(userData)
+	client := new(Client)
+	client.SetUser(user)
+	// Test.
+	req := request.Scripts{Stop: []string{"test.ash"}}
+	_, err = handleScriptsRequest(client, req)
+	if err == nil {
+		t.Errorf("Scripts request from non-admin user was not rejected")
+	}
+	user.Admin = true
+	resp, err := handleScriptsRequest(client, req)
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	if !script.Stopped() {
+		t.Errorf("Script was not stopped")
+	}
+	if len(resp) != 1 || resp[0].Name != "test.ash" {
+		t.Errorf("Invalid scripts response: %v", resp)
+	}
+}
+
+// TestReloadScripts tests starting new scripts on scripts reload.
+func TestReloadScripts(t *testing.T) {
+	testWorkDir(t)
+	game = newGame(modData)
+	// Create script file.
+	err := os.MkdirAll(game.moduleScriptsPath(), 0755)
+	if err != nil {
+		t.Fatalf("Unable to create scripts dir: %v", err)
+	}
+	path := filepath.Join(game.moduleScriptsPath(), "test.ash")
+	err = os.WriteFile(path, []byte("true { wait(1000); };"), 0644)
+	if err != nil {
+		t.Fatalf("Unable to create script file: %v", err)
+	}
+	name := game.scriptName(path)
+	defer game.StopScript(name)
+	// Test.
+	reloaded, err := game.ReloadScripts()
+	if err != nil {
+		t.Fatalf("Unable to reload scripts: %v", err)
+	}
+	if len(reloaded) != 1 || reloaded[0] != name {
+		t.Fatalf("New script was not started: %v", reloaded)
+	}
+	reloaded, err = game.ReloadScripts()
+	if err != nil {
+		t.Fatalf("Unable to reload scripts: %v", err)
+	}
+	if len(reloaded) > 0 {
+		t.Errorf("Started script was started again: %v", reloaded)
+	}
+}
+
+// TestHandleScheduleRequest tests handling schedule request.
+func TestHandleScheduleRequest(t *testing.T) {
+	// Create game & schedule.
+	game = newGame(modData)
+	scheduleData := res.ScheduleData{
+		ID:     "test",
+		Script: "schedule/test.ash",
+		Hour:   4,
+		Daily:  true,
+	}
+	game.schedule = newScheduler(scheduleData)
+	// Create user & client.
+	user := user.New(userData)
+	user.Admin = true
+	client := new(Client)
+	client.SetUser(user)
+	// Test.
+	resp, err := handleScheduleRequest(client)
+	if err != nil {
+		t.Fatalf("Request handling error: %v", err)
+	}
+	if len(resp) != 1 {
+		t.Fatalf("Invalid number of scheduled scripts: %d != 1", len(resp))
+	}
+	if resp[0].At != "04:00" {
+		t.Errorf("Invalid scheduled time: %s != 04:00", resp[0].At)
+	}
+	next := time.UnixMilli(resp[0].Next)
+	if !next.After(time.Now()) || next.Hour() != 4 {
+		t.Errorf("Invalid next run time: %v", next)
+	}
+}
+
+// TestSchedulerRunSkip tests skipping scheduled runs of
+// scripts that are still running.
+func TestSchedulerRunSkip(t *testing.T) {
+	game = newGame(modData)
+	scheduleData := res.ScheduleData{
+		ID:       "test",
+		Script:   "schedule/test.ash",
+		Interval: 1,
+	}
+	game.schedule = newScheduler(scheduleData)
+	path := filepath.Join(game.Conf().Path, config.ModuleServerPath,
+		scheduleData.Script)
+	script, err := newGameScript(game.scriptName(path), path,
+		"true { wait(1000); };", time.Now())
+	if err != nil {
+		t.Fatalf("Unable to create script: %v", err)
+	}
+	game.scripts.Add(script)
+	defer game.scripts.Remove(script)
+	// Test.
+	time.Sleep(time.Millisecond)
+	game.schedule.Run(game)
+	resp := game.schedule.Response()
+	if resp[0].Runs != 0 {
+		t.Errorf("Scheduled script started while previous instance is running")
+	}
+}
+
+// TestNewGameScriptCommands tests restricting burn commands
+// available for server scripts.
+func TestNewGameScriptCommands(t *testing.T) {
+	text := "true { engineexport -o module; };"
+	_, err := newGameScript("test.ash", "", text, time.Now())
+	if err == nil {
+		t.Errorf("Script with not allowed command was not rejected")
+	}
+	text = "@1 = out(engineexport -o module)\ntrue { wait(1); };"
+	_, err = newGameScript("test.ash", "", text, time.Now())
+	if err == nil {
+		t.Errorf("Script with not allowed var command was not rejected")
+	}
+	text = "true { objectset -o chat -a hello -t char#0; };"
+	_, err = newGameScript("test.ash", "", text, time.Now())
+	if err != nil {
+		t.Errorf("Script with allowed command was rejected: %v", err)
+	}
+}
+
+// TestSetAshVars tests setting script vars by the server
+// update loop.
+func TestSetAshVars(t *testing.T) {
+	text := "@1 = out(engineshow -o echo -a value)\ntrue { objectshow -o position -t @1; };"
+	script, err := newGameScript("test.ash", "", text, time.Now())
+	if err != nil {
+		t.Fatalf("Unable to create script: %v", err)
+	}
+	if len(script.vars) != 1 || script.vars[0].ID != 1 {
+		t.Fatalf("Script var was not parsed: %v", script.vars)
+	}
+	// Test.
+	go func() {
+		expr := <-scriptExprs
+		expr.Result <- scriptResult{0, "char#1"}
+	}()
+	script.start = time.Now()
+	err = setAshVars(script)
+	if err != nil {
+		t.Fatalf("Unable to set script vars: %v", err)
+	}
+	exprText := script.Blocks()[0].Expressions()[0].BurnExpr().String()
+	if !strings.Contains(exprText, "char#1") {
+		t.Errorf("Script var was not set: %s", exprText)
+	}
+	text = "@12 = out(engineshow -o echo -a value)\ntrue { wait(1); };"
+	_, err = newGameScript("test.ash", "", text, time.Now())
+	if err == nil {
+		t.Errorf("Script with multi-digit var was not rejected")
+	}
+}
+
+// TestAshCaseArgID tests parsing argument IDs of script
+// for cases.
+func TestAshCaseArgID(t *testing.T) {
+	text := "true {\n\tfor(@12 = out(moduleshow -o area-chars -t area)) {\n\t\twait(1);\n\t};\n};"
+	script, err := ash.NewScript("test.ash", text)
+	if err != nil {
+		t.Fatalf("Unable to create script: %v", err)
+	}
+	forCase := script.Blocks()[0].Blocks()[0].Condition()
+	if !ashForCase(forCase) {
+		t.Errorf("For case was not detected: %s", forCase.String())
+	}
+	if id := ashCaseArgID(forCase); id != 12 {
+		t.Errorf("Invalid case arg ID: %d != 12", id)
+	}
+	text = "format == 1 {\n\twait(1);\n};"
+	script, err = ash.NewScript("test.ash", text)
+	if err != nil {
+		t.Fatalf("Unable to create script: %v", err)
+	}
+	if ashForCase(script.Blocks()[0].Condition()) {
+		t.Errorf("Comparison case was detected as for case")
+	}
+}
+
+// TestReplayJournal tests replaying of journal entries.
+func TestReplayJournal(t *testing.T) {
+	// Create game.
+	game = newGame(modData)
+	// Create characters.
+	charFromData := charData
+	charFromData.ID = "charFrom"
+	charFrom := character.New(charFromData)
+	charToData := charData
+	charToData.ID = "charTo"
+	charTo := character.New(charToData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(charFrom)
+	area.AddObject(charTo)
+	// Add item.
+	game.Update(1)
+	it := item.NewMisc(itemData)
+	charFrom.Inventory().AddItem(it)
+	// Create entries.
+	items := map[string][]string{it.ID(): []string{it.Serial()}}
+	entries := []res.JournalEntryData{
+		res.JournalEntryData{Seq: 1, Type: baseEntry, Save: "save"},
+		res.JournalEntryData{Seq: 1, Type: transferEntry,
+			Transfers: []res.JournalTransferData{transferData(charTo, charFrom, items)}},
+		res.JournalEntryData{Seq: 2, Type: transferEntry,
+			Transfers: []res.JournalTransferData{transferData(charFrom, charTo, items)}},
+	}
+	// Test replay.
+	replayJournal(entries)
+	if charFrom.Inventory().Item(it.ID(), it.Serial()) != nil {
+		t.Errorf("Item should be removed from %s inventory: %s %s", charFrom.ID(),
+			it.ID(), it.Serial())
+	}
+	if charTo.Inventory().Item(it.ID(), it.Serial()) == nil {
+		t.Errorf("Item should be added to %s inventory: %s %s", charTo.ID(),
+			it.ID(), it.Serial())
+	}
+	if game.pause {
+		t.Errorf("Game should be unpaused after replay")
+	}
+}
+
+// TestReplayJournalChapter tests replaying of chapter change
+// before the start of the server update loop.
+func TestReplayJournalChapter(t *testing.T) {
+	testWorkDir(t)
+	// Create game.
+	game = newGame(modData)
+	char := character.New(charData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	entries := []res.JournalEntryData{
+		res.JournalEntryData{Seq: 1, Type: baseEntry, Save: "save"},
+		res.JournalEntryData{Seq: 2, Type: chapterEntry, CharID: char.ID(),
+			Serial: char.Serial(), Chapter: game.Conf().Chapter},
+	}
+	// Test replay.
+	replayed := make(chan bool)
+	go func() {
+		replayJournal(entries)
+		replayed <- true
+	}()
+	select {
+	case <-replayed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Journal replay with chapter entry didn't finish")
+	}
+}
+
+// TestReplayJournalEscrow tests replaying of journal entries
+// with items moved to and from the escrow.
+func TestReplayJournalEscrow(t *testing.T) {
+	// Create game.
+	game = newGame(modData)
+	// Create characters.
+	charFromData := charData
+	charFromData.ID = "charFrom"
+	charFrom := character.New(charFromData)
+	charToData := charData
+	charToData.ID = "charTo"
+	charTo := character.New(charToData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(charFrom)
+	area.AddObject(charTo)
+	// Add items.
+	game.Update(1)
+	removed := item.NewMisc(itemData)
+	charFrom.Inventory().AddItem(removed)
+	inserted := item.NewMisc(itemData)
+	escrow.Hold(inserted)
+	// Create entries.
+	removedItems := map[string][]string{removed.ID(): []string{removed.Serial()}}
+	insertedItems := map[string][]string{inserted.ID(): []string{inserted.Serial()}}
+	entries := []res.JournalEntryData{
+		res.JournalEntryData{Seq: 1, Type: baseEntry, Save: "save"},
+		res.JournalEntryData{Seq: 2, Type: transferEntry,
+			Transfers: []res.JournalTransferData{transferData(charFrom, nil, removedItems)}},
+		res.JournalEntryData{Seq: 3, Type: transferEntry,
+			Transfers: []res.JournalTransferData{transferData(nil, charTo, insertedItems)}},
+		res.JournalEntryData{Seq: 4, Type: transferEntry,
+			Transfers: []res.JournalTransferData{transferData(charTo, charFrom, insertedItems)}},
+		res.JournalEntryData{Seq: 5, Type: abortEntry, Aborted: 4},
+	}
+	// Test replay.
+	replayJournal(entries)
+	if charFrom.Inventory().Item(removed.ID(), removed.Serial()) != nil {
+		t.Errorf("Item should be removed from %s inventory: %s %s", charFrom.ID(),
+			removed.ID(), removed.Serial())
+	}
+	if charTo.Inventory().Item(inserted.ID(), inserted.Serial()) == nil {
+		t.Errorf("Item should be added to %s inventory: %s %s", charTo.ID(),
+			inserted.ID(), inserted.Serial())
+	}
+	if escrow.items[inserted.ID()+inserted.Serial()] != nil {
+		t.Errorf("Item should be released from the escrow: %s %s",
+			inserted.ID(), inserted.Serial())
+	}
+	if charFrom.Inventory().Item(inserted.ID(), inserted.Serial()) != nil {
+		t.Errorf("Aborted entry should not be replayed")
+	}
+}
+
+// TestInjectUserChars tests placing of saved user characters
+// in the game world.
+func TestInjectUserChars(t *testing.T) {
+	// Create game.
+	game = newGame(modData)
+	// Create user with saved character.
+	savedCharData := charData
+	savedCharData.ID = "savedChar"
+	savedCharData.Serial = "0"
+	savedChar := res.SavedCharData{
+		Time: time.Now().Add(-time.Minute).UnixMilli(),
+		Area: "area",
+		PosX: 10,
+		PosY: 20,
+		Char: savedCharData,
+	}
+	data := userData
+	data.SavedChars = []res.SavedCharData{savedChar}
+	user := user.New(data)
+	// Test inject.
+	game.injectUserChars(user)
+	char := game.Chapter().Character(savedCharData.ID, savedCharData.Serial)
+	if char == nil {
+		t.Fatalf("Saved character should be placed in the game world")
+	}
+	if x, y := char.Position(); x != 10 || y != 20 {
+		t.Errorf("Invalid character position: %f %f", x, y)
+	}
+	if !user.Owns(char.ID(), char.Serial()) {
+		t.Errorf("Saved character should be owned by the user")
+	}
+}
+
+// TestInjectUserCharsReplay tests placing of saved user characters
+// in the game world after the journal replay.
+func TestInjectUserCharsReplay(t *testing.T) {
+	testWorkDir(t)
+	// Create game & character.
+	game = newGame(modData)
+	replayedCharData := charData
+	replayedCharData.ID = "replayedChar"
+	replayedCharData.Serial = "0"
+	char := character.New(replayedCharData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Create user with saved character.
+	savedChar := res.SavedCharData{
+		Time: time.Now().Add(-time.Minute).UnixMilli(),
+		Area: "area",
+		Char: replayedCharData,
+	}
+	data := userData
+	data.SavedChars = []res.SavedCharData{savedChar}
+	user := user.New(data)
+	// Replay journal.
+	it := item.NewMisc(itemData)
+	escrow.Hold(it)
+	items := map[string][]string{it.ID(): []string{it.Serial()}}
+	entries := []res.JournalEntryData{
+		res.JournalEntryData{Seq: 1, Type: baseEntry, Save: "save"},
+		res.JournalEntryData{Seq: 2, Type: transferEntry,
+			Time:      time.Now().Add(-time.Second).UnixMilli(),
+			Transfers: []res.JournalTransferData{transferData(nil, char, items)}},
+	}
+	replayJournal(entries)
+	// Test login.
+	game.ActivateUserChars(user)
+	loggedChar := game.Chapter().Character(char.ID(), char.Serial())
+	if loggedChar != char {
+		t.Fatalf("Replayed character should not be replaced by the saved character")
+	}
+	if loggedChar.Inventory().Item(it.ID(), it.Serial()) == nil {
+		t.Errorf("Replayed item should be in the character inventory")
+	}
+}
+
+// TestInjectUserCharsSave tests placing of saved user characters
+// in the game world loaded from the save newer than the characters.
+func TestInjectUserCharsSave(t *testing.T) {
+	testWorkDir(t)
+	// Create game & character.
+	game = newGame(modData)
+	savedCharData := charData
+	savedCharData.ID = "snapshotChar"
+	savedCharData.Serial = "0"
+	char := character.New(savedCharData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	// Create user with saved character.
+	savedChar := res.SavedCharData{
+		Time: time.Now().Add(-time.Minute).UnixMilli(),
+		Area: "area",
+		Char: savedCharData,
+	}
+	data := userData
+	data.SavedChars = []res.SavedCharData{savedChar}
+	user := user.New(data)
+	// Replay journal.
+	entries := []res.JournalEntryData{
+		res.JournalEntryData{Seq: 1, Type: baseEntry, Save: "save",
+			SaveTime: time.Now().Add(-time.Second).UnixMilli()},
+	}
+	replayJournal(entries)
+	// Test login.
+	game.ActivateUserChars(user)
+	if game.Chapter().Character(char.ID(), char.Serial()) != char {
+		t.Errorf("Character from the save should not be replaced by the older saved character")
+	}
+}
+
+// TestServerShutdown tests scheduling and canceling of
+// TestDeactivateUserCharsDespawn tests removing user characters
+// from the game world with the despawn logout policy and restoring
+// them on the last position.
+func TestDeactivateUserCharsDespawn(t *testing.T) {
+	testWorkDir(t)
+	logoutPolicy := config.LogoutPolicy
+	config.LogoutPolicy = config.LogoutDespawn
+	defer func() { config.LogoutPolicy = logoutPolicy }()
+	// Create game & character.
+	game = newGame(modData)
+	despawnCharData := charData
+	despawnCharData.ID = "despawnChar"
+	char := character.New(despawnCharData)
+	area := game.Chapter().Area("area")
+	if area == nil {
+		t.Fatalf("Test area not found")
+	}
+	area.AddObject(char)
+	char.SetPosition(10, 20)
+	user := user.New(userData)
+	user.AddChar(char)
+	// Test deactivate.
+	game.DeactivateUserChars(user)
+	if game.Chapter().Character(char.ID(), char.Serial()) != nil {
+		t.Errorf("Character should be removed from the game world")
+	}
+	offlineChars := user.OfflineChars()
+	if len(offlineChars) != 1 {
+		t.Fatalf("Invalid number of offline characters: %d", len(offlineChars))
+	}
+	if offlineChars[0].Area != "area" || offlineChars[0].PosX != 10 ||
+		offlineChars[0].PosY != 20 {
+		t.Errorf("Invalid offline character position: %s %f %f", offlineChars[0].Area,
+			offlineChars[0].PosX, offlineChars[0].PosY)
+	}
+	// Test activate.
+	game.ActivateUserChars(user)
+	char = game.Chapter().Character(char.ID(), char.Serial())
+	if char == nil {
+		t.Fatalf("Offline character should be restored in the game world")
+	}
+	if game.Chapter().ObjectArea(char) != area {
+		t.Errorf("Character restored in invalid area")
+	}
+	if x, y := char.Position(); x != 10 || y != 20 {
+		t.Errorf("Invalid character position: %f %f", x, y)
+	}
+	if len(user.OfflineChars()) > 0 {
+		t.Errorf("Restored character should be removed from offline characters")
+	}
+}
+
+// TestActivateUserCharsStartArea tests restoring offline character
+// from area that does not exist anymore.
+func TestActivateUserCharsStartArea(t *testing.T) {
+	testWorkDir(t)
+	// Create game.
+	game = newGame(modData)
+	game.Chapter().Conf().StartArea = "area"
+	game.Chapter().Conf().StartPosX = 5
+	game.Chapter().Conf().StartPosY = 6
+	// Create user with offline character.
+	offlineCharData := charData
+	offlineCharData.ID = "offlineChar"
+	offlineCharData.Serial = "0"
+	user := user.New(userData)
+	user.AddOfflineChar(res.OfflineCharData{
+		Char: offlineCharData,
+		Area: "removedArea",
+		PosX: 10,
+		PosY: 20,
+	})
+	// Test.
+	game.ActivateUserChars(user)
+	char := game.Chapter().Character(offlineCharData.ID, offlineCharData.Serial)
+	if char == nil {
+		t.Fatalf("Offline character should be restored in the game world")
+	}
+	if game.Chapter().ObjectArea(char) != game.Chapter().Area("area") {
+		t.Errorf("Character should be restored in the chapter start area")
+	}
+	if x, y := char.Position(); x != 5 || y != 6 {
+		t.Errorf("Invalid character position: %f %f", x, y)
+	}
+}
+
+// the server shutdown.
+func TestServerShutdown(t *testing.T) {
+	s := new(serverShutdown)
+	err := s.Cancel()
+	if err == nil {
+		t.Errorf("Canceling not scheduled shutdown should return an error")
+	}
+	s.Schedule(time.Now().Add(time.Hour))
+	if !s.Scheduled() {
+		t.Fatalf("Shutdown should be scheduled")
+	}
+	if len(s.timers) != len(shutdownWarnings)+1 {
+		t.Errorf("Invalid number of countdown timers: %d", len(s.timers))
+	}
+	err = s.Cancel()
+	if err != nil {
+		t.Fatalf("Unable to cancel shutdown: %v", err)
+	}
+	if s.Scheduled() || len(s.timers) > 0 {
+		t.Errorf("Shutdown should be canceled")
+	}
+	if close {
+		t.Errorf("Server should not be closed")
+	}
+}
+
+// TestHandleReloadConfigRequest tests handling of reload
+// config request.
+func TestHandleReloadConfigRequest(t *testing.T) {
+	// Create user & client.
+	user := user.New(userData)
+	client := new(Client)
+	client.SetUser(user)
+	// Test non-admin.
+	_, err := handleReloadConfigRequest(client)
+	if err == nil {
+		t.Errorf("Request handling didn't returned non-admin error")
+	}
+}
+
+// TestReloadConfig tests reloading of the server configuration.
+func TestReloadConfig(t *testing.T) {
+	testWorkDir(t)
+	updateBreak, message := config.UpdateBreak, config.Message
+	defer func() { config.UpdateBreak, config.Message = updateBreak, message }()
+	config.UpdateBreak, config.Message = 1, ""
+	// Test valid config.
+	conf := "update-break:5\nmessage:reloaded\n"
+	err := os.WriteFile(config.ConfigFileName, []byte(conf), 0644)
+	if err != nil {
+		t.Fatalf("Unable to write config file: %v", err)
+	}
+	keys, err := reloadConfig("test")
+	if err != nil {
+		t.Fatalf("Unable to reload config: %v", err)
+	}
+	if strings.Join(keys, " ") != "message update-break" {
+		t.Errorf("Invalid changed keys: %v", keys)
+	}
+	if config.UpdateBreak != 5 {
+		t.Errorf("Invalid update break: %d != 5", config.UpdateBreak)
+	}
+	// Test invalid config.
+	for _, v := range []string{"NaN", "Inf", "-1"} {
+		conf = "update-break:7\naction-min-range:" + v + "\n"
+		err = os.WriteFile(config.ConfigFileName, []byte(conf), 0644)
+		if err != nil {
+			t.Fatalf("Unable to write config file: %v", err)
+		}
+		_, err = reloadConfig("test")
+		if err == nil {
+			t.Errorf("Invalid float value was accepted: %s", v)
+		}
+		if config.UpdateBreak != 5 {
+			t.Errorf("Values should be unchanged after failed reload: %d != 5",
+				config.UpdateBreak)
+		}
+	}
+}
+
+// TestCloseServer tests saving of the game and closing of
+// client connections on the server close.
+func TestCloseServer(t *testing.T) {
+	testWorkDir(t)
+	game = newGame(modData)
+	close = true
+	defer func() { close = false }()
+	// Create client connection.
+	conns := make(chan *websocket.Conn, 1)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Unable to upgrade connection: %v", err)
+			return
+		}
+		conns <- conn
+	}
+	httpServer := httptest.NewServer(http.HandlerFunc(handler))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer conn.Close()
+	client := newClient(<-conns)
+	// Test close.
+	closeServer(map[string]*Client{"client": client})
+	if !game.stopped.Load() {
+		t.Errorf("Game should be stopped")
+	}
+	saves, err := moduleSaves(modData.ID + autosaveInfix)
+	if err != nil {
+		t.Fatalf("Unable to list game saves: %v", err)
+	}
+	if len(saves) != 1 {
+		t.Errorf("Invalid number of game saves: %d != 1", len(saves))
+	}
+	resp := <-client.Out
+	if !resp.Closed {
+		t.Errorf("Client should receive closed response")
+	}
+	<-shutdownDone
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Client connection should be closed: %v", err)
+	}
+}
+
+// TestScriptText tests making script arguments from user text.
+func TestScriptText(t *testing.T) {
+	text := scriptText("hi -t other#0 |t objectset; 'x' @1 -a")
+	if text != "\"hi other#0 objectset x 1\"" {
+		t.Errorf("Invalid script text: %s", text)
+	}
+}
+
+// TestSaveSnapshot tests removing of the oldest autosave snapshots
+// and listing saves with the saves request.
+func TestSaveSnapshot(t *testing.T) {
+	testWorkDir(t)
+	snapshots := config.AutosaveSnapshots
+	defer func() { config.AutosaveSnapshots = snapshots }()
+	config.AutosaveSnapshots = 2
+	// Create old snapshots and save.
+	err := os.MkdirAll(config.ModulesPath, 0755)
+	if err != nil {
+		t.Fatalf("Unable to create modules dir: %v", err)
+	}
+	names := []string{modData.ID + autosaveInfix + "20260101-000001",
+		modData.ID + autosaveInfix + "20260101-000002", "save"}
+	for i, n := range names {
+		path := filepath.Join(config.ModulesPath, n+flamedata.ModuleFileExt)
+		err := os.WriteFile(path, nil, 0644)
+		if err != nil {
+			t.Fatalf("Unable to create save file: %v", err)
+		}
+		modTime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		err = os.Chtimes(path, modTime, modTime)
+		if err != nil {
+			t.Fatalf("Unable to set save time: %v", err)
+		}
+	}
+	// Test snapshot.
+	err = saveSnapshot(modData, 0, time.Now())
+	if err != nil {
+		t.Fatalf("Unable to save snapshot: %v", err)
+	}
+	saves, err := moduleSaves(modData.ID + autosaveInfix)
+	if err != nil {
+		t.Fatalf("Unable to list snapshots: %v", err)
+	}
+	if len(saves) != 2 {
+		t.Fatalf("Invalid number of snapshots: %d != 2", len(saves))
+	}
+	if saves[0].Name != names[1] {
+		t.Errorf("Oldest snapshot was not removed: %s", saves[0].Name)
+	}
+	// Test saves request.
+	client := new(Client)
+	client.SetUser(user.New(userData))
+	_, err = handleSavesRequest(client)
+	if err == nil {
+		t.Errorf("Saves request from non-admin user was not rejected")
+	}
+	client.User().Admin = true
+	saves, err = handleSavesRequest(client)
+	if err != nil {
+		t.Fatalf("Unable to handle saves request: %v", err)
+	}
+	if len(saves) != 3 {
+		t.Errorf("Invalid number of saves: %d != 3", len(saves))
+	}
+}
@@ -0,0 +1,33 @@
+/*
+ * charlist.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package response
+
+// Struct for character list response.
+type CharList struct {
+	ID      string `json:"id"`
+	Serial  string `json:"serial"`
+	Level   int    `json:"level"`
+	Race    string `json:"race"`
+	Sex     string `json:"sex"`
+	Area    string `json:"area"`
+	Active  bool   `json:"active"`
+	Offline bool   `json:"offline"`
+}
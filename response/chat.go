@@ -1,7 +1,7 @@
 /*
  * chat.go
  *
- * Copyright (C) 2022 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2022-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -29,4 +29,5 @@ type Chat struct {
 	Message      string    `json:"message"`
 	Translated   bool      `json:"translated"`
 	Time         time.Time `json:"time"`
+	Channel      string    `json:"channel"`
 }
@@ -0,0 +1,31 @@
+/*
+ * market.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package response
+
+// Struct for marketplace listing response.
+type MarketListing struct {
+	ID         int    `json:"id"`
+	SellerID   string `json:"seller-id"`
+	ItemID     string `json:"item-id"`
+	ItemSerial string `json:"item-serial"`
+	Price      int    `json:"price"`
+	Expires    int64  `json:"expires"`
+}
@@ -0,0 +1,44 @@
+/*
+ * party.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package response
+
+// Struct for party response.
+type Party struct {
+	ID      int      `json:"id"`
+	Leader  string   `json:"leader"`
+	Members []string `json:"members"`
+	Loot    string   `json:"loot"`
+}
+
+// Struct for party invite response.
+type PartyInvite struct {
+	ID   int    `json:"id"`
+	From string `json:"from"`
+}
+
+// Struct for party roll response.
+type PartyRoll struct {
+	ID         int    `json:"id"`
+	ItemID     string `json:"item-id"`
+	ItemSerial string `json:"item-serial"`
+	Winner     string `json:"winner"`
+	Roll       int    `json:"roll"`
+}
@@ -1,7 +1,7 @@
 /*
  * response.go
  *
- * Copyright (C) 2020-2024 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -36,11 +36,26 @@ type Response struct {
 	Update         Update                 `json:"update"`
 	ChangeChapter  bool                   `json:"change-chapter"`
 	Character      []Character            `json:"character"`
+	CharList       []CharList             `json:"char-list"`
 	Trade          []Trade                `json:"trade"`
 	TradeCompleted []TradeCompleted       `json:"trade-completed"`
+	TradeStatus    []TradeStatus          `json:"trade-status"`
 	Dialog         []res.ObjectDialogData `json:"dialog"`
 	Use            []Use                  `json:"use"`
 	Chat           []Chat                 `json:"chat"`
+	ChatHistory    []ChatRecord           `json:"chat-history"`
+	Market         []MarketListing        `json:"market"`
+	Mail           []Mail                 `json:"mail"`
+	PartyInvite    []PartyInvite          `json:"party-invite"`
+	PartyRoll      []PartyRoll            `json:"party-roll"`
+	Guild          []Guild                `json:"guild"`
+	GuildInvite    []GuildInvite          `json:"guild-invite"`
+	Friends        []Friend               `json:"friends"`
+	Presence       []Friend               `json:"presence"`
+	Scripts        []Script               `json:"scripts"`
+	Schedule       []ScheduledScript      `json:"schedule"`
+	Saves          []Save                 `json:"saves"`
+	ReloadConfig   []string               `json:"reload-config"`
 	Command        []Command              `json:"command"`
 	Load           Load                   `json:"load"`
 	Error          []string               `json:"error"`
@@ -0,0 +1,32 @@
+/*
+ * schedule.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package response
+
+// Struct for scheduled script response.
+type ScheduledScript struct {
+	ID       string `json:"id"`
+	Script   string `json:"script"`
+	Interval int64  `json:"interval"`
+	At       string `json:"at"`
+	Next     int64  `json:"next"`
+	Last     int64  `json:"last"`
+	Runs     int    `json:"runs"`
+}
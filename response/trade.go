@@ -1,7 +1,7 @@
 /*
  * trade.go
  *
- * Copyright (C) 2020 Dariusz Sikora <dev@isangeles.pl>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -20,6 +20,15 @@
 
 package response
 
+// Trade statuses.
+const (
+	TradeCountered = "countered"
+	TradeDeclined  = "declined"
+	TradeCanceled  = "canceled"
+	TradeExpired   = "expired"
+	TradeFailed    = "failed"
+)
+
 // Struct for trade response.
 type Trade struct {
 	ID           int                 `json:"id"`
@@ -33,3 +42,9 @@ type Trade struct {
 
 // Struct for trade completed response.
 type TradeCompleted Trade
+
+// Struct for trade status response.
+type TradeStatus struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
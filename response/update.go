@@ -1,7 +1,7 @@
 /*
  * response.go
  *
- * Copyright (C) 2020-2025 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -27,5 +27,7 @@ import (
 // Struct for update response.
 type Update struct {
 	Module  res.ModuleData `json:"module"`
+	Paths   []Path         `json:"paths"`
+	Party   Party          `json:"party"`
 	Message string         `json:"message"`
 }
@@ -0,0 +1,163 @@
+/*
+ * schedule.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/response"
+)
+
+// Name of the schedule file in the module server directory.
+const scheduleFile = ".schedule"
+
+// Struct for scheduler of server scripts.
+type scheduler struct {
+	mutex   sync.Mutex
+	entries []*scheduledScript
+}
+
+// Struct for scheduled script.
+type scheduledScript struct {
+	res.ScheduleData
+	next time.Time
+	last time.Time
+	runs int
+}
+
+// newScheduler creates new scheduler for specified scheduled
+// scripts.
+func newScheduler(data ...res.ScheduleData) *scheduler {
+	s := new(scheduler)
+	for _, d := range data {
+		entry := scheduledScript{ScheduleData: d}
+		entry.next = entry.nextRun(time.Now())
+		s.entries = append(s.entries, &entry)
+	}
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].ID < s.entries[j].ID
+	})
+	return s
+}
+
+// Run starts all scripts that are due to run by specified game.
+// Missed runs are not repeated, the next run of the started
+// script is scheduled from the current time.
+// Runs of scripts with the previous instance still running
+// are skipped.
+func (s *scheduler) Run(g *Game) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	for _, e := range s.entries {
+		if now.Before(e.next) {
+			continue
+		}
+		e.next = e.nextRun(now)
+		path := filepath.Join(g.Conf().Path, config.ModuleServerPath, e.Script)
+		if len(g.scripts.Named(g.scriptName(path))) > 0 {
+			log.Printf("Game: scheduled script still running: %s", e.ID)
+			continue
+		}
+		e.last = now
+		e.runs++
+		script, err := g.importScript(path)
+		if err != nil {
+			log.Printf("Game: unable to import scheduled script: %s: %v",
+				e.ID, err)
+			continue
+		}
+		go g.runScript(script)
+	}
+}
+
+// Response returns response with status of all scheduled
+// scripts.
+func (s *scheduler) Response() (resp []response.ScheduledScript) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, e := range s.entries {
+		entryResp := response.ScheduledScript{
+			ID:       e.ID,
+			Script:   e.Script,
+			Interval: e.Interval,
+			Next:     e.next.UnixMilli(),
+			Runs:     e.runs,
+		}
+		if e.Daily {
+			entryResp.At = fmt.Sprintf("%02d:%02d", e.Hour, e.Minute)
+		}
+		if !e.last.IsZero() {
+			entryResp.Last = e.last.UnixMilli()
+		}
+		resp = append(resp, entryResp)
+	}
+	return
+}
+
+// nextRun returns time of the next run of the scheduled
+// script after specified time.
+func (e *scheduledScript) nextRun(after time.Time) time.Time {
+	if !e.Daily {
+		return after.Add(time.Duration(e.Interval) * time.Millisecond)
+	}
+	next := time.Date(after.Year(), after.Month(), after.Day(), e.Hour,
+		e.Minute, 0, 0, after.Location())
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// loadSchedule loads scheduled scripts from the schedule file
+// in the module server directory.
+func (g *Game) loadSchedule() error {
+	g.schedule = newScheduler()
+	path := filepath.Join(g.Conf().Path, config.ModuleServerPath, scheduleFile)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	schedule, err := data.ImportSchedule(path)
+	if err != nil {
+		return fmt.Errorf("unable to import schedule: %v", err)
+	}
+	g.schedule = newScheduler(schedule...)
+	return nil
+}
+
+// handleScheduleRequest handles schedule request.
+// Only admin users are allowed to check the script schedule.
+func handleScheduleRequest(cli *Client) ([]response.ScheduledScript, error) {
+	if !cli.User().Admin {
+		return nil, fmt.Errorf("You are not an admin")
+	}
+	return game.schedule.Response(), nil
+}
@@ -0,0 +1,382 @@
+/*
+ * scriptrun.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isangeles/burn"
+	"github.com/isangeles/burn/ash"
+	"github.com/isangeles/burn/syntax"
+
+	"github.com/isangeles/fire/config"
+)
+
+// Time between checks of the script state during wait
+// macro.
+const scriptWaitStep = 100 * time.Millisecond
+
+// Struct for burn expression from server script,
+// executed by the server update loop.
+type scriptExpr struct {
+	Expr   burn.Expression
+	Result chan scriptResult
+}
+
+// Struct for result of burn expression.
+type scriptResult struct {
+	Res int
+	Out string
+}
+
+// Struct for script variable set by the output of
+// burn expression.
+type scriptVar struct {
+	ID   int
+	Expr burn.Expression
+}
+
+var (
+	scriptExprs       = make(chan scriptExpr)
+	ashCaseArgPattern = regexp.MustCompile(`^for\(\s*@(\d+)\s*=`)
+)
+
+// runAsh runs specified server script until the script is stopped
+// or all script blocks are ended.
+// Script marked to run once is stopped after the first pass through
+// its blocks.
+// Works like ash.Run, but all burn expressions are checked against
+// the script limits from the config package, and executed by the
+// server update loop, to avoid races with request handling.
+func runAsh(s *gameScript) error {
+	defer s.Stop(true)
+	err := setAshVars(s)
+	if err != nil {
+		return fmt.Errorf("unable to set script vars: %v", err)
+	}
+	for !s.Stopped() && !s.Ended() {
+		for _, b := range s.Blocks() {
+			if s.Stopped() {
+				return nil
+			}
+			err := runAshBlock(s, b)
+			if err != nil {
+				return fmt.Errorf("unable to run script block: %v", err)
+			}
+		}
+		if s.once {
+			return nil
+		}
+	}
+	return nil
+}
+
+// setAshVars executes var expressions of specified server script
+// by the server update loop, and inserts the results into the
+// script blocks.
+func setAshVars(s *gameScript) error {
+	if len(s.vars) < 1 {
+		return nil
+	}
+	blocks := s.Blocks()
+	for _, v := range s.vars {
+		r, o, err := execAsh(s, v.Expr)
+		if err != nil {
+			return err
+		}
+		if r != 0 {
+			return fmt.Errorf("unable to run var expr: '%s': [%d]%s",
+				v.Expr.String(), r, o)
+		}
+		name := fmt.Sprintf("%s%d", ash.VarPrefix, v.ID)
+		for i, b := range blocks {
+			blocks[i] = b.SetVariable(name, scriptSafeText(o))
+		}
+	}
+	return validateScriptBlocks(blocks)
+}
+
+// runAshBlock runs specified block of specified server script.
+func runAshBlock(s *gameScript, blk *ash.ScriptBlock) error {
+	if blk.Stopped() || s.Stopped() {
+		return nil
+	}
+	// Check condition.
+	meet, out, err := meetAshCase(s, blk.Condition())
+	if err != nil {
+		return fmt.Errorf("unable to check block condition: %v", err)
+	}
+	if !meet {
+		return nil
+	}
+	vars := strings.Fields(out)
+	if !ashForCase(blk.Condition()) {
+		vars = append(vars, "")
+	}
+	argID := ashCaseArgID(blk.Condition())
+	for _, v := range vars {
+		if argID > 0 {
+			blk = blk.SetVariable(fmt.Sprintf("@%d", argID), v)
+		}
+		// Execute expressions.
+	exprs:
+		for _, e := range blk.Expressions() {
+			switch e.Type() {
+			case ash.WaitMacro:
+				err := waitAsh(s, e.WaitTime())
+				if err != nil {
+					return err
+				}
+				continue
+			case ash.EndMacro:
+				blk.Stop(true)
+				break exprs
+			}
+			r, o, err := execAsh(s, e.BurnExpr())
+			if err != nil {
+				return err
+			}
+			if r != 0 {
+				return fmt.Errorf("unable to run expr: '%s': [%d]%s",
+					e.BurnExpr().String(), r, o)
+			}
+			if e.Type() == ash.EchoMacro {
+				log.Printf("Script: %s: %s", s.Name(), o)
+			}
+		}
+		// Inner blocks.
+		for _, b := range blk.Blocks() {
+			b.Stop(false) // if block was previously stopped with end macro
+			err := runAshBlock(s, b)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	blk.SetExecuteCounter(blk.ExecuteCounter() + 1)
+	return nil
+}
+
+// meetAshCase checks if specified case of specified server
+// script is meet and returns condition expression output.
+func meetAshCase(s *gameScript, c *ash.ScriptCase) (bool, string, error) {
+	if len(c.String()) < 1 || strings.HasPrefix(c.String(), ash.TrueKeyword) {
+		return true, "", nil
+	}
+	r, o, err := execAsh(s, c.Expression())
+	if err != nil {
+		return false, "", err
+	}
+	if r != 0 {
+		return false, "", fmt.Errorf("unable to run condition expr: '%s': [%d]%s",
+			c.Expression().String(), r, o)
+	}
+	meet, err := c.CorrectRes(o)
+	if err != nil {
+		return false, "", fmt.Errorf("unable to check result: %v", err)
+	}
+	return meet, o, nil
+}
+
+// execAsh executes specified burn expression of specified server
+// script by the server update loop.
+// Script that exceeded the commands limit from the config package
+// is paused until the end of the current one-second window.
+// Returns error if the script exceeded the maximal runtime.
+func execAsh(s *gameScript, expr burn.Expression) (int, string, error) {
+	if time.Since(s.window) >= time.Second {
+		s.window = time.Now()
+		s.commands = 0
+	}
+	s.commands += len(expr.Commands())
+	config.RLock()
+	limit := config.ScriptCommandLimit
+	config.RUnlock()
+	if limit > 0 && s.commands > limit {
+		err := waitAsh(s, time.Until(s.window.Add(time.Second)).Milliseconds())
+		if err != nil {
+			return 0, "", err
+		}
+		s.window = time.Now()
+		s.commands = len(expr.Commands())
+	}
+	err := checkAshRuntime(s)
+	if err != nil {
+		return 0, "", err
+	}
+	result := make(chan scriptResult, 1)
+	scriptExprs <- scriptExpr{expr, result}
+	r := <-result
+	return r.Res, r.Out, nil
+}
+
+// waitAsh pauses specified server script for specified number
+// of milliseconds, or until the script is stopped.
+func waitAsh(s *gameScript, millis int64) error {
+	end := time.Now().Add(time.Duration(millis) * time.Millisecond)
+	for time.Now().Before(end) && !s.Stopped() {
+		err := checkAshRuntime(s)
+		if err != nil {
+			return err
+		}
+		step := time.Until(end)
+		if step > scriptWaitStep {
+			step = scriptWaitStep
+		}
+		time.Sleep(step)
+	}
+	return nil
+}
+
+// checkAshRuntime checks if specified server script doesn't exceed
+// the maximal script runtime from the config package.
+func checkAshRuntime(s *gameScript) error {
+	config.RLock()
+	maxRuntime := time.Duration(config.ScriptMaxRuntime) * time.Millisecond
+	config.RUnlock()
+	if maxRuntime > 0 && time.Since(s.start) > maxRuntime {
+		return fmt.Errorf("runtime limit exceeded: %dms", maxRuntime.Milliseconds())
+	}
+	return nil
+}
+
+// ashForCase checks if specified script case is a for case.
+// Case types are checked in the same order as in the ash parser,
+// so comparison cases starting with 'for' are not for cases.
+func ashForCase(c *ash.ScriptCase) bool {
+	text := c.String()
+	if strings.Contains(text, "<") || strings.Contains(text, "!=") ||
+		strings.Contains(text, "==") {
+		return false
+	}
+	return strings.HasPrefix(text, "for")
+}
+
+// ashCaseArgID returns ID of the script argument set by
+// specified for case, or 0 if the case sets no argument.
+func ashCaseArgID(c *ash.ScriptCase) int {
+	if !ashForCase(c) {
+		return 0
+	}
+	match := ashCaseArgPattern.FindStringSubmatch(c.String())
+	if len(match) < 2 {
+		return 0
+	}
+	argID, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	return argID
+}
+
+// validateScriptBlocks checks if specified script blocks use only
+// burn commands allowed for server scripts by the config package.
+func validateScriptBlocks(blocks []*ash.ScriptBlock) error {
+	for _, b := range blocks {
+		err := validateScriptExpr(b.Condition().Expression())
+		if err != nil {
+			return err
+		}
+		for _, e := range b.Expressions() {
+			if e.Type() == ash.WaitMacro || e.Type() == ash.EndMacro {
+				continue
+			}
+			err := validateScriptExpr(e.BurnExpr())
+			if err != nil {
+				return err
+			}
+		}
+		err = validateScriptBlocks(b.Blocks())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseScriptVars parses variable declarations with burn
+// expressions from specified script text, and checks if these
+// expressions use only burn commands allowed for server scripts
+// by the config package.
+// Returns script text with the parsed declarations replaced by
+// placeholders, so the expressions are not executed on the script
+// creation, and the parsed variables, to set by the server update
+// loop before running the script.
+// Only single-digit var IDs are supported by the ash parser.
+func parseScriptVars(text string) (string, []scriptVar, error) {
+	var vars []scriptVar
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if !strings.HasPrefix(l, ash.VarPrefix) || !strings.Contains(l, "=") {
+			continue
+		}
+		val := strings.TrimSpace(l[strings.Index(l, "=")+1:])
+		if !strings.HasPrefix(val, ash.OutKeyword+"(") {
+			continue
+		}
+		end := strings.LastIndex(val, ")")
+		if end < 0 {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(l[len(ash.VarPrefix):strings.Index(l, "=")]))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid var id: %v", err)
+		}
+		if id < 1 || id > 9 {
+			return "", nil, fmt.Errorf("var id out of range(1-9): %d", id)
+		}
+		expr, err := syntax.NewSTDExpression(val[len(ash.OutKeyword)+1 : end])
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid var expression: %v", err)
+		}
+		err = validateScriptExpr(expr)
+		if err != nil {
+			return "", nil, err
+		}
+		vars = append(vars, scriptVar{id, expr})
+		lines[i] = fmt.Sprintf("%s%d = %s%d", ash.VarPrefix, id, ash.VarPrefix, id)
+	}
+	return strings.Join(lines, "\n"), vars, nil
+}
+
+// validateScriptExpr checks if specified burn expression uses only
+// commands allowed for server scripts by the config package.
+func validateScriptExpr(expr burn.Expression) error {
+	if expr == nil {
+		return nil
+	}
+	config.RLock()
+	defer config.RUnlock()
+	for _, c := range expr.Commands() {
+		if len(c.Tool()) < 1 {
+			continue
+		}
+		if !contains(config.ScriptCommands, c.Tool()) {
+			return fmt.Errorf("command not allowed: %s", c.Tool())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+/*
+ * scripts.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isangeles/burn"
+	"github.com/isangeles/burn/ash"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/response"
+)
+
+// Name of the burn tool for managing server scripts.
+const scriptsTool = "scripts"
+
+// Struct for server scripts running in the game.
+type gameScripts struct {
+	mutex   sync.RWMutex
+	scripts []*gameScript
+	started map[string]bool
+}
+
+// Struct for server script.
+type gameScript struct {
+	*ash.Script
+	path     string
+	args     []string
+	vars     []scriptVar
+	loaded   time.Time
+	start    time.Time
+	commands int
+	window   time.Time
+	once     bool
+}
+
+// newGameScripts creates new container for running scripts.
+func newGameScripts() *gameScripts {
+	gs := gameScripts{started: make(map[string]bool)}
+	return &gs
+}
+
+// Add adds specified script to the running scripts.
+func (gs *gameScripts) Add(s *gameScript) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.scripts = append(gs.scripts, s)
+}
+
+// Remove removes specified script from the running scripts.
+func (gs *gameScripts) Remove(s *gameScript) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	for i, rs := range gs.scripts {
+		if rs == s {
+			gs.scripts = append(gs.scripts[:i], gs.scripts[i+1:]...)
+			return
+		}
+	}
+}
+
+// List returns all running scripts.
+func (gs *gameScripts) List() []*gameScript {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+	return append([]*gameScript{}, gs.scripts...)
+}
+
+// Named returns all running scripts with specified name.
+func (gs *gameScripts) Named(name string) (scripts []*gameScript) {
+	for _, s := range gs.List() {
+		if s.Name() == name {
+			scripts = append(scripts, s)
+		}
+	}
+	return
+}
+
+// Started checks if script from file with specified path was
+// ever started.
+func (gs *gameScripts) Started(path string) bool {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+	return gs.started[path]
+}
+
+// SetStarted marks script from file with specified path as
+// started.
+func (gs *gameScripts) SetStarted(path string) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.started[path] = true
+}
+
+// newGameScript creates new server script with specified name from
+// specified text loaded from file with specified path.
+// Returns error if the script uses burn commands not allowed
+// by the config package.
+func newGameScript(name, path, text string, loaded time.Time, args ...string) (*gameScript, error) {
+	text, vars, err := parseScriptVars(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script vars: %v", err)
+	}
+	script, err := ash.NewScript(name, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create script: %v", err)
+	}
+	err = validateScriptBlocks(script.Blocks())
+	if err != nil {
+		return nil, fmt.Errorf("invalid script: %v", err)
+	}
+	s := gameScript{
+		Script: script,
+		path:   path,
+		args:   args,
+		vars:   vars,
+		loaded: loaded,
+	}
+	return &s, nil
+}
+
+// Ended checks if all blocks of the script were ended.
+func (s *gameScript) Ended() bool {
+	for _, b := range s.Blocks() {
+		if !b.Stopped() {
+			return false
+		}
+	}
+	return true
+}
+
+// Changed checks if the script file was modified after
+// the script was loaded.
+func (s *gameScript) Changed() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false
+	}
+	return info.ModTime().After(s.loaded)
+}
+
+// importScript creates new server script from file with
+// specified path.
+func (g *Game) importScript(path string, args ...string) (*gameScript, error) {
+	loaded := time.Now()
+	text, err := data.ImportScriptSource(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import script source: %v", err)
+	}
+	return newGameScript(g.scriptName(path), path, text, loaded, args...)
+}
+
+// runScript runs specified server script.
+// The script is stopped after all its blocks were ended.
+// Script errors are reported to all admin users.
+func (g *Game) runScript(script *gameScript) {
+	script.start = time.Now()
+	g.scripts.Add(script)
+	defer g.scripts.Remove(script)
+	err := runAsh(script)
+	if err != nil {
+		log.Printf("Game: unable to run ash script: %s: %v", script.Name(),
+			err)
+		err := fmt.Sprintf("Script failed: %s: %v", script.Name(), err)
+		notifyAdmins(response.Response{Error: []string{err}})
+	}
+}
+
+// StopScript stops all running scripts with specified name.
+func (g *Game) StopScript(name string) error {
+	scripts := g.scripts.Named(name)
+	if len(scripts) < 1 {
+		return fmt.Errorf("Script not running: %s", name)
+	}
+	for _, s := range scripts {
+		s.Stop(true)
+	}
+	return nil
+}
+
+// RestartScript stops all running scripts with specified name
+// and starts them again, with the same arguments, from the current
+// version of the script file.
+// Scripts that can't be restarted are left running.
+func (g *Game) RestartScript(name string) error {
+	scripts := g.scripts.Named(name)
+	if len(scripts) < 1 {
+		return fmt.Errorf("Script not running: %s", name)
+	}
+	var errs []string
+	for _, s := range scripts {
+		err := g.restartScript(s)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Unable to restart all scripts: %s",
+			strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ReloadScripts reloads event scripts, restarts all running
+// scripts with files modified after the scripts were started,
+// and starts new module, chapter, and occupied areas scripts.
+// Scripts that can't be restarted are left running.
+// Returns names of restarted and started scripts.
+func (g *Game) ReloadScripts() ([]string, error) {
+	err := g.loadEventScripts()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load event scripts: %v", err)
+	}
+	var reloaded, errs []string
+	for _, s := range g.scripts.List() {
+		if !s.Changed() {
+			continue
+		}
+		err := g.restartScript(s)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		reloaded = append(reloaded, s.Name())
+	}
+	paths := []string{g.moduleScriptsPath(), g.chapterScriptsPath()}
+	for _, id := range g.events.OccupiedAreas() {
+		paths = append(paths, g.areaScriptsPath(id))
+	}
+	for _, p := range paths {
+		started, err := g.startScripts(p, true)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Unable to start scripts: %v", err))
+			continue
+		}
+		reloaded = append(reloaded, started...)
+	}
+	if len(errs) > 0 {
+		return reloaded, fmt.Errorf("Unable to reload all scripts: %s",
+			strings.Join(errs, ", "))
+	}
+	return reloaded, nil
+}
+
+// restartScript stops specified script and runs its new
+// instance from the script file.
+func (g *Game) restartScript(s *gameScript) error {
+	script, err := g.importScript(s.path, s.args...)
+	if err != nil {
+		return fmt.Errorf("Unable to import script: %s: %v", s.Name(), err)
+	}
+	script.once = s.once
+	s.Stop(true)
+	go g.runScript(script)
+	return nil
+}
+
+// scriptName returns name for script with specified path,
+// relative to the module server directory.
+func (g *Game) scriptName(path string) string {
+	serverPath := filepath.Join(g.Conf().Path, config.ModuleServerPath)
+	name, err := filepath.Rel(serverPath, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(name)
+}
+
+// handleScriptsRequest handles scripts request.
+// Only admin users are allowed to manage server scripts.
+func handleScriptsRequest(cli *Client, req request.Scripts) ([]response.Script, error) {
+	if !cli.User().Admin {
+		return nil, fmt.Errorf("You are not an admin")
+	}
+	for _, n := range req.Stop {
+		err := game.StopScript(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, n := range req.Restart {
+		err := game.RestartScript(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.Reload {
+		restarted, err := game.ReloadScripts()
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Scripts reloaded: %v", restarted)
+	}
+	return scriptsResponse(), nil
+}
+
+// scriptsResponse creates response with all running
+// scripts.
+func scriptsResponse() (resp []response.Script) {
+	for _, s := range game.scripts.List() {
+		scriptResp := response.Script{
+			Name:    s.Name(),
+			Args:    s.args,
+			Runtime: time.Since(s.start).Milliseconds(),
+		}
+		resp = append(resp, scriptResp)
+	}
+	return
+}
+
+// handleScriptsCommand handles scripts burn command.
+// Available options: list, stop, restart, reload.
+// Stop and restart options require script names as
+// target args.
+func handleScriptsCommand(cmd burn.Command) (int, string) {
+	if len(cmd.OptionArgs()) < 1 {
+		return 2, fmt.Sprintf("%s: no option args", scriptsTool)
+	}
+	out := ""
+	switch cmd.OptionArgs()[0] {
+	case "list":
+		for _, s := range scriptsResponse() {
+			out = fmt.Sprintf("%s%s(%dms) ", out, s.Name, s.Runtime)
+		}
+	case "stop":
+		for _, n := range cmd.TargetArgs() {
+			err := game.StopScript(n)
+			if err != nil {
+				return 3, fmt.Sprintf("%s: %v", scriptsTool, err)
+			}
+		}
+	case "restart":
+		for _, n := range cmd.TargetArgs() {
+			err := game.RestartScript(n)
+			if err != nil {
+				return 3, fmt.Sprintf("%s: %v", scriptsTool, err)
+			}
+		}
+	case "reload":
+		restarted, err := game.ReloadScripts()
+		if err != nil {
+			return 3, fmt.Sprintf("%s: %v", scriptsTool, err)
+		}
+		for _, n := range restarted {
+			out = fmt.Sprintf("%s%s ", out, n)
+		}
+	default:
+		return 2, fmt.Sprintf("%s: no such option: %s", scriptsTool,
+			cmd.OptionArgs()[0])
+	}
+	return 0, strings.TrimSpace(out)
+}
+
+// notifyAdmins sends specified response to all admin users.
+func notifyAdmins(resp response.Response) {
+	for _, u := range data.Users() {
+		if !u.Admin {
+			continue
+		}
+		userResponses <- userResponse{Response: resp, UserID: u.ID()}
+	}
+}
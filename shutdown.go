@@ -0,0 +1,237 @@
+/*
+ * shutdown.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/isangeles/burn"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/response"
+)
+
+const shutdownTool = "shutdown"
+
+var (
+	server       *http.Server
+	shutdown     = new(serverShutdown)
+	shutdownDone = make(chan bool)
+	// Time before the shutdown of countdown announcements.
+	shutdownWarnings = []time.Duration{10 * time.Minute, 5 * time.Minute,
+		time.Minute, 30 * time.Second, 10 * time.Second}
+)
+
+// Struct for scheduled server shutdown.
+type serverShutdown struct {
+	mutex  sync.Mutex
+	time   time.Time
+	timers []*time.Timer
+}
+
+// Schedule schedules the server shutdown at specified time
+// and announces countdown to all logged clients.
+// Previously scheduled shutdown is canceled.
+func (s *serverShutdown) Schedule(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if close {
+		return
+	}
+	s.stopTimers()
+	s.time = t
+	log.Printf("Server going down at: %v", t)
+	broadcast(shutdownMessage(time.Until(t)), false)
+	for _, w := range shutdownWarnings {
+		w := w
+		if time.Until(t) <= w {
+			continue
+		}
+		warn := func() { broadcast(shutdownMessage(w), false) }
+		s.timers = append(s.timers, time.AfterFunc(time.Until(t)-w, warn))
+	}
+	start := func() { close = true }
+	s.timers = append(s.timers, time.AfterFunc(time.Until(t), start))
+}
+
+// Cancel cancels the scheduled shutdown.
+func (s *serverShutdown) Cancel() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.time.IsZero() {
+		return fmt.Errorf("no shutdown scheduled")
+	}
+	if close {
+		return fmt.Errorf("shutdown already started")
+	}
+	s.stopTimers()
+	s.time = time.Time{}
+	log.Printf("Server shutdown canceled")
+	broadcast("Server shutdown canceled", false)
+	return nil
+}
+
+// Time returns time of the scheduled shutdown, or zero time
+// if there is no shutdown scheduled.
+func (s *serverShutdown) Time() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.time
+}
+
+// Scheduled checks if the server shutdown is scheduled.
+func (s *serverShutdown) Scheduled() bool {
+	return !s.Time().IsZero()
+}
+
+// stopTimers stops all countdown timers.
+func (s *serverShutdown) stopTimers() {
+	for _, t := range s.timers {
+		t.Stop()
+	}
+	s.timers = nil
+}
+
+// shutdownMessage returns countdown message for specified
+// time left to the shutdown.
+func shutdownMessage(left time.Duration) string {
+	if left < time.Second {
+		return "Server is going down now"
+	}
+	return fmt.Sprintf("Server is going down in %v", left.Round(time.Second))
+}
+
+// handleSignals starts graceful shutdown of the server after
+// receiving interrupt or termination signal.
+// Second signal terminates the server immediately.
+func handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	log.Printf("Received signal: %v", sig)
+	shutdown.Schedule(time.Now())
+	sig = <-signals
+	log.Printf("Received signal: %v: terminating", sig)
+	os.Exit(1)
+}
+
+// closeServer stops the game, saves current server configuration,
+// users, and the game, sends closed response to all specified clients,
+// and shuts down the HTTP server.
+// Should be called by the update loop, after setting the close flag.
+func closeServer(clients map[string]*Client) {
+	game.Stop()
+	game.Wait()
+	err := config.Save()
+	if err != nil {
+		log.Printf("Unable to save config: %v", err)
+	}
+	for _, c := range clients {
+		if c.User() != nil {
+			game.SaveUserChars(c.User())
+		}
+	}
+	err = data.SaveUsers(config.UsersPath)
+	if err != nil {
+		log.Printf("Unable to save users: %v", err)
+	}
+	err = saveSnapshot(game.Data(), journal.Seq(), time.Now())
+	if err != nil {
+		log.Printf("Unable to save game: %v", err)
+	}
+	for _, c := range clients {
+		updateClient(c, response.Response{})
+	}
+	conns := make([]*Client, 0, len(clients))
+	for _, c := range clients {
+		conns = append(conns, c)
+	}
+	go closeConnections(conns)
+}
+
+// closeConnections closes connections of all specified clients and
+// shuts down the HTTP server.
+// Waits some time before closing connections to ensure that all clients
+// will receive the closed response.
+func closeConnections(clients []*Client) {
+	time.Sleep(2 * time.Second)
+	for _, c := range clients {
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server closed")
+		c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		c.Conn.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if server != nil {
+		err := server.Shutdown(ctx)
+		if err != nil {
+			log.Printf("Unable to shut down HTTP server: %v", err)
+		}
+	}
+	log.Printf("Server closed")
+	shutdownDone <- true
+}
+
+// handleShutdownCommand handles shutdown command.
+func handleShutdownCommand(cmd burn.Command) (int, string) {
+	if len(cmd.OptionArgs()) < 1 {
+		return 2, fmt.Sprintf("%s: no option args", shutdownTool)
+	}
+	switch cmd.OptionArgs()[0] {
+	case "now":
+		shutdown.Schedule(time.Now())
+	case "in":
+		if len(cmd.TargetArgs()) < 1 {
+			return 2, fmt.Sprintf("%s: no target args", shutdownTool)
+		}
+		secs, err := strconv.Atoi(cmd.TargetArgs()[0])
+		if err != nil {
+			return 3, fmt.Sprintf("%s: invalid seconds value: %v", shutdownTool, err)
+		}
+		shutdown.Schedule(time.Now().Add(time.Duration(secs) * time.Second))
+	case "cancel":
+		err := shutdown.Cancel()
+		if err != nil {
+			return 3, fmt.Sprintf("%s: %v", shutdownTool, err)
+		}
+	case "show":
+		if !shutdown.Scheduled() {
+			return 0, "none"
+		}
+		return 0, fmt.Sprintf("%v", shutdown.Time())
+	default:
+		return 2, fmt.Sprintf("%s: no such option: %s", shutdownTool,
+			cmd.OptionArgs()[0])
+	}
+	return 0, ""
+}
@@ -1,7 +1,7 @@
 /*
  * user.go
  *
- * Copyright (C) 2020-2025 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -22,6 +22,8 @@
 package user
 
 import (
+	"time"
+
 	"github.com/isangeles/flame/character"
 	"github.com/isangeles/flame/flag"
 
@@ -30,12 +32,21 @@ import (
 
 // Struct for user.
 type User struct {
-	Logged    bool
-	Admin     bool
-	id        string
-	pass      string
-	charFlags []flag.Flag
-	chars     map[string]Character
+	Logged       bool
+	Admin        bool
+	MaxChars     int
+	MutedUntil   int64
+	id           string
+	pass         string
+	charFlags    []flag.Flag
+	friends      []string
+	ignored      []string
+	chars        map[string]Character
+	activeChars  map[string]Character
+	selected     bool
+	offlineChars map[string]res.OfflineCharData
+	savedChars   map[string]res.SavedCharData
+	mail         []res.MailData
 }
 
 // Struct for user character.
@@ -46,14 +57,28 @@ type Character struct {
 // New creates new user.
 func New(data res.UserData) *User {
 	u := User{
-		id:    data.ID,
-		pass:  data.Pass,
-		Admin: data.Admin,
-		chars: make(map[string]Character),
+		id:           data.ID,
+		pass:         data.Pass,
+		Admin:        data.Admin,
+		MaxChars:     data.MaxChars,
+		MutedUntil:   data.MutedUntil,
+		chars:        make(map[string]Character),
+		activeChars:  make(map[string]Character),
+		offlineChars: make(map[string]res.OfflineCharData),
+		savedChars:   make(map[string]res.SavedCharData),
+		mail:         data.Mail,
+		friends:      data.Friends,
+		ignored:      data.Ignored,
 	}
 	for _, f := range data.CharFlags {
 		u.charFlags = append(u.charFlags, flag.Flag(f))
 	}
+	for _, c := range data.OfflineChars {
+		u.offlineChars[c.Char.ID+c.Char.Serial] = c
+	}
+	for _, c := range data.SavedChars {
+		u.savedChars[c.Char.ID+c.Char.Serial] = c
+	}
 	return &u
 }
 
@@ -77,16 +102,58 @@ func (u *User) Chars() (chars []Character) {
 
 // AddChar adds user's flags to specified character and adds
 // this character to the user characters list.
+// If the user has selected active characters, the character
+// is added to the active characters as well.
 func (u *User) AddChar(char *character.Character) {
 	for _, f := range u.charFlags {
 		char.AddFlag(f)
 	}
-	u.chars[char.ID()+char.Serial()] = Character{char.ID(), char.Serial()}
+	userChar := Character{char.ID(), char.Serial()}
+	u.chars[char.ID()+char.Serial()] = userChar
+	if u.selected {
+		u.activeChars[char.ID()+char.Serial()] = userChar
+	}
 }
 
 // RemoveChar removes character from user characters list.
+// The selection of active characters is kept, so removing the last
+// selected character leaves the user without active characters.
 func (u *User) RemoveChar(char Character) {
 	delete(u.chars, char.ID+char.Serial)
+	delete(u.activeChars, char.ID+char.Serial)
+}
+
+// ActiveChars returns user characters selected
+// as active.
+func (u *User) ActiveChars() (chars []Character) {
+	for _, char := range u.Chars() {
+		if u.Active(char.ID, char.Serial) {
+			chars = append(chars, char)
+		}
+	}
+	return
+}
+
+// SetActiveChars sets specified characters as active
+// user characters.
+// If no characters are specified, then all user characters
+// are active.
+func (u *User) SetActiveChars(chars ...Character) {
+	u.activeChars = make(map[string]Character)
+	u.selected = len(chars) > 0
+	for _, c := range chars {
+		u.activeChars[c.ID+c.Serial] = c
+	}
+}
+
+// Active checks if character with specified ID and serial
+// value is selected as active user character.
+func (u *User) Active(id, serial string) bool {
+	if !u.selected {
+		return true
+	}
+	_, ok := u.activeChars[id+serial]
+	return ok
 }
 
 // CharFlags returns a list of flags that identifies
@@ -95,13 +162,172 @@ func (u *User) CharFlags() []flag.Flag {
 	return u.charFlags
 }
 
+// Owns checks if object with specified ID and serial
+// value is one of the user characters.
+func (u *User) Owns(id, serial string) bool {
+	for _, c := range u.Chars() {
+		if c.ID+c.Serial == id+serial {
+			return true
+		}
+	}
+	return false
+}
+
 // Controls checks if user controls object with
 // specified ID and serial value.
+// Only characters selected as active are controlled
+// by the user.
 func (u *User) Controls(id, serial string) bool {
-	for _, c := range u.Chars() {
-		if c.ID+c.Serial == id+serial {
+	return u.Owns(id, serial) && u.Active(id, serial)
+}
+
+// OfflineChars returns data of user characters
+// removed from the game world.
+func (u *User) OfflineChars() (chars []res.OfflineCharData) {
+	for _, char := range u.offlineChars {
+		chars = append(chars, char)
+	}
+	return
+}
+
+// AddOfflineChar adds specified data to the offline
+// characters of the user.
+func (u *User) AddOfflineChar(data res.OfflineCharData) {
+	u.offlineChars[data.Char.ID+data.Char.Serial] = data
+}
+
+// RemoveOfflineChar removes data of character with specified
+// ID and serial value from the offline characters of the user.
+func (u *User) RemoveOfflineChar(id, serial string) {
+	delete(u.offlineChars, id+serial)
+}
+
+// SavedChars returns data of user characters saved
+// independently of the game world.
+func (u *User) SavedChars() (chars []res.SavedCharData) {
+	for _, char := range u.savedChars {
+		chars = append(chars, char)
+	}
+	return
+}
+
+// SetSavedChar sets specified data as saved data of
+// the user character.
+func (u *User) SetSavedChar(data res.SavedCharData) {
+	u.savedChars[data.Char.ID+data.Char.Serial] = data
+}
+
+// RemoveSavedChar removes saved data of character with specified
+// ID and serial value.
+func (u *User) RemoveSavedChar(id, serial string) {
+	delete(u.savedChars, id+serial)
+}
+
+// Muted checks if the user is muted.
+func (u *User) Muted() bool {
+	return time.Now().UnixMilli() < u.MutedUntil
+}
+
+// Mail returns all mail from the user mailbox.
+func (u *User) Mail() []res.MailData {
+	return u.mail
+}
+
+// MailByID returns mail with specified ID from the user
+// mailbox, or nil if there is no such mail.
+func (u *User) MailByID(id int) *res.MailData {
+	for i := range u.mail {
+		if u.mail[i].ID == id {
+			return &u.mail[i]
+		}
+	}
+	return nil
+}
+
+// AddMail adds specified mail to the user mailbox.
+// The mail receives a new ID, unique in the mailbox.
+func (u *User) AddMail(mail res.MailData) {
+	mail.ID = 1
+	for _, m := range u.mail {
+		if m.ID >= mail.ID {
+			mail.ID = m.ID + 1
+		}
+	}
+	u.mail = append(u.mail, mail)
+}
+
+// RemoveMail removes mail with specified ID from the user
+// mailbox.
+func (u *User) RemoveMail(id int) {
+	for i, m := range u.mail {
+		if m.ID == id {
+			u.mail = append(u.mail[:i], u.mail[i+1:]...)
+			return
+		}
+	}
+}
+
+// Friends returns IDs of all friends of the user.
+func (u *User) Friends() []string {
+	return u.friends
+}
+
+// AddFriend adds user with specified ID to the user friends.
+func (u *User) AddFriend(id string) {
+	if !u.Friend(id) {
+		u.friends = append(u.friends, id)
+	}
+}
+
+// RemoveFriend removes user with specified ID from the user friends.
+func (u *User) RemoveFriend(id string) {
+	u.friends = remove(u.friends, id)
+}
+
+// Friend checks if user with specified ID is a friend of the user.
+func (u *User) Friend(id string) bool {
+	return contains(u.friends, id)
+}
+
+// Ignored returns IDs of all users ignored by the user.
+func (u *User) Ignored() []string {
+	return u.ignored
+}
+
+// Ignore adds user with specified ID to the users ignored by the user.
+func (u *User) Ignore(id string) {
+	if !u.Ignores(id) {
+		u.ignored = append(u.ignored, id)
+	}
+}
+
+// Unignore removes user with specified ID from the users ignored
+// by the user.
+func (u *User) Unignore(id string) {
+	u.ignored = remove(u.ignored, id)
+}
+
+// Ignores checks if user with specified ID is ignored by the user.
+func (u *User) Ignores(id string) bool {
+	return contains(u.ignored, id)
+}
+
+// contains checks if specified slice contains specified value.
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
 			return true
 		}
 	}
 	return false
 }
+
+// remove returns slice without specified value.
+func remove(s []string, v string) (out []string) {
+	for _, e := range s {
+		if e != v {
+			out = append(out, e)
+		}
+	}
+	return
+}
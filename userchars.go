@@ -0,0 +1,134 @@
+/*
+ * userchars.go
+ *
+ * Copyright (C) 2026 Dariusz Sikora <ds@isangeles.dev>
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/isangeles/flame/character"
+
+	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
+	"github.com/isangeles/fire/user"
+)
+
+// autosaveUsersChars saves characters of all logged users if
+// there was the chars save interval from the config package passed
+// since the last save.
+func (g *Game) autosaveUsersChars() {
+	if config.CharsSaveInterval < 1 {
+		return
+	}
+	interval := time.Duration(config.CharsSaveInterval) * time.Millisecond
+	if g.lastCharsSave.IsZero() {
+		g.lastCharsSave = time.Now()
+	}
+	if time.Since(g.lastCharsSave) < interval {
+		return
+	}
+	g.lastCharsSave = time.Now()
+	for _, u := range data.Users() {
+		if u.Logged {
+			g.SaveUserChars(u)
+		}
+	}
+}
+
+// SaveUserChars saves current state of all game characters
+// owned by the specified user in the user directory, independently
+// of the game world.
+func (g *Game) SaveUserChars(usr *user.User) {
+	chars := g.userOwnedChars(usr)
+	if len(chars) < 1 {
+		return
+	}
+	for _, c := range chars {
+		savedChar := res.SavedCharData{
+			Time: time.Now().UnixMilli(),
+			Char: c.Data(),
+		}
+		savedChar.PosX, savedChar.PosY = c.Position()
+		area := g.Chapter().ObjectArea(c)
+		if area != nil {
+			savedChar.Area = area.ID()
+		}
+		usr.SetSavedChar(savedChar)
+	}
+	saveUser(usr)
+}
+
+// injectUserChars places saved characters of the specified user
+// in the game world.
+// Only characters saved before the game start are injected, replacing
+// characters with the same ID and serial value loaded with the game
+// module, as all characters saved after the game start are already
+// in the game world in their latest state.
+// Characters saved before the game save loaded on the game start, or
+// before the last journal entry replayed on the game start, are not
+// injected either, since the game world already contains their newer
+// state.
+// Characters removed from the game world after the user logout
+// are skipped, since they are restored from the offline characters
+// data.
+func (g *Game) injectUserChars(usr *user.User) {
+	offlineChars := make(map[string]bool)
+	for _, c := range usr.OfflineChars() {
+		offlineChars[c.Char.ID+c.Char.Serial] = true
+	}
+	for _, c := range usr.SavedChars() {
+		if offlineChars[c.Char.ID+c.Char.Serial] {
+			continue
+		}
+		if g.Chapter().Character(c.Char.ID, c.Char.Serial) != nil &&
+			(c.Time > g.started.UnixMilli() || c.Time <= g.restored.UnixMilli()) {
+			continue
+		}
+		err := g.injectChar(c)
+		if err != nil {
+			log.Printf("Game: unable to inject user character: %s: %s %s: %v",
+				usr.ID(), c.Char.ID, c.Char.Serial, err)
+			continue
+		}
+		char := g.Chapter().Character(c.Char.ID, c.Char.Serial)
+		if char != nil {
+			usr.AddChar(char)
+		}
+	}
+}
+
+// injectChar places character from specified saved data in the game
+// world, in place of the character with the same ID and serial value.
+func (g *Game) injectChar(savedChar res.SavedCharData) error {
+	prevChar := g.Chapter().Character(savedChar.Char.ID, savedChar.Char.Serial)
+	if prevChar != nil {
+		area := g.Chapter().ObjectArea(prevChar)
+		if area != nil {
+			area.RemoveObject(prevChar)
+		}
+	}
+	g.removeCharData(savedChar.Char.ID, savedChar.Char.Serial)
+	g.Chapter().Resources().Characters = append(g.Chapter().Resources().Characters,
+		savedChar.Char)
+	char := character.New(savedChar.Char)
+	return g.placeChar(char, savedChar.Area, savedChar.PosX, savedChar.PosY)
+}
@@ -1,7 +1,7 @@
 /*
  * utils.go
  *
- * Copyright (C) 2020-2023 Dariusz Sikora <ds@isangeles.dev>
+ * Copyright (C) 2020-2026 Dariusz Sikora <ds@isangeles.dev>
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU Affero General Public License as published by
@@ -22,6 +22,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/isangeles/flame/character"
@@ -33,42 +34,147 @@ import (
 	"github.com/isangeles/flame/useaction"
 
 	"github.com/isangeles/fire/config"
+	"github.com/isangeles/fire/data"
+	"github.com/isangeles/fire/data/res"
 	"github.com/isangeles/fire/request"
+	"github.com/isangeles/fire/user"
 )
 
-// transferItems transfer items between specified objects.
+// Struct for items transaction.
+// Transaction validates all items transfers before
+// applying them, and reverts all applied transfers
+// in case of an error.
+type itemsTransaction struct {
+	transfers []itemTransfer
+	done      []itemTransfer
+	items     map[item.Item]bool
+	journal   []res.JournalTransferData
+}
+
+// Struct for single item transfer.
+type itemTransfer struct {
+	from, to item.Container
+	item     item.Item
+}
+
+// newItemsTransaction creates new items transaction.
+func newItemsTransaction() *itemsTransaction {
+	t := itemsTransaction{items: make(map[item.Item]bool)}
+	return &t
+}
+
+// Add validates and adds transfer of specified items between specified
+// objects to the transaction.
 // Items are in the form of a map with IDs as keys and serial values as values.
-func transferItems(from, to item.Container, items map[string][]string) error {
+// A nil 'to' container means that the items will be removed from the 'from'
+// container.
+func (t *itemsTransaction) Add(from, to item.Container, items map[string][]string) error {
 	for id, serials := range items {
 		for _, serial := range serials {
-			item := from.Inventory().Item(id, serial)
-			if item == nil {
+			it := from.Inventory().Item(id, serial)
+			if it == nil {
 				return fmt.Errorf("Item not found: %s %s",
 					id, serial)
 			}
-			from.Inventory().RemoveItem(item)
-			to.Inventory().AddItem(item)
+			if t.items[it] {
+				return fmt.Errorf("Item already transferred: %s %s",
+					id, serial)
+			}
+			t.items[it] = true
+			t.transfers = append(t.transfers, itemTransfer{from, to, it})
 		}
 	}
+	t.journal = append(t.journal, transferData(from, to, items))
 	return nil
 }
 
-// removeItems removes items from specified container.
-// Items are in the form of a map with IDs as keys and serial values as values.
-func removeItems(container item.Container, items map[string][]string) error {
-	for id, serials := range items {
-		for _, serial := range serials {
-			item := container.Inventory().Item(id, serial)
-			if item == nil {
-				return fmt.Errorf("Item not found: %s %s",
-					id, serial)
+// Insert validates and adds insertion of specified items from outside
+// of the game world, e.g. from the server escrow, to specified container
+// to the transaction.
+func (t *itemsTransaction) Insert(to item.Container, items ...item.Item) error {
+	serials := make(map[string][]string)
+	for _, it := range items {
+		if t.items[it] {
+			return fmt.Errorf("Item already transferred: %s %s",
+				it.ID(), it.Serial())
+		}
+		t.items[it] = true
+		t.transfers = append(t.transfers, itemTransfer{nil, to, it})
+		serials[it.ID()] = append(serials[it.ID()], it.Serial())
+	}
+	t.journal = append(t.journal, transferData(nil, to, serials))
+	return nil
+}
+
+// Transfers returns journal data of all transfers from
+// the transaction.
+func (t *itemsTransaction) Transfers() []res.JournalTransferData {
+	return t.journal
+}
+
+// Commit applies all transfers from the transaction.
+// In case of an error, all applied transfers are reverted.
+func (t *itemsTransaction) Commit() error {
+	for _, tr := range t.transfers {
+		if tr.from != nil {
+			tr.from.Inventory().RemoveItem(tr.item)
+		}
+		if tr.to != nil {
+			err := tr.to.Inventory().AddItem(tr.item)
+			if err != nil {
+				if tr.from != nil {
+					tr.from.Inventory().AddItem(tr.item)
+				}
+				t.rollback()
+				return fmt.Errorf("Unable to add item: %s %s: %v",
+					tr.item.ID(), tr.item.Serial(), err)
 			}
-			container.Inventory().RemoveItem(item)
 		}
+		t.done = append(t.done, tr)
 	}
 	return nil
 }
 
+// rollback reverts all applied transfers.
+func (t *itemsTransaction) rollback() {
+	for i := len(t.done) - 1; i >= 0; i-- {
+		tr := t.done[i]
+		if tr.to != nil {
+			tr.to.Inventory().RemoveItem(tr.item)
+		}
+		if tr.from != nil {
+			tr.from.Inventory().AddItem(tr.item)
+		}
+	}
+	t.done = nil
+}
+
+// transferItems transfer items between specified objects.
+// Items are in the form of a map with IDs as keys and serial values as values.
+// No items are transferred if any of the items can't be transferred.
+// The transfer is recorded in the journal.
+func transferItems(from, to item.Container, items map[string][]string) error {
+	tx := newItemsTransaction()
+	err := tx.Add(from, to, items)
+	if err != nil {
+		return err
+	}
+	return journalCommit(transferEntry, tx)
+}
+
+// removeItems removes items from specified container.
+// Items are in the form of a map with IDs as keys and serial values as values.
+// No items are removed if any of the items can't be removed.
+// The removal is recorded in the journal.
+func removeItems(container item.Container, items map[string][]string) error {
+	tx := newItemsTransaction()
+	err := tx.Add(container, nil, items)
+	if err != nil {
+		return err
+	}
+	return journalCommit(transferEntry, tx)
+}
+
 // charSkillRecipe returns skill or recipe with specified ID from the character,
 // or nil if character does not have skill or recipe with such ID.
 func charSkillRecipe(char *character.Character, id string) useaction.Usable {
@@ -98,6 +204,8 @@ func inRange(ob1, ob2 serial.Serialer) bool {
 	if !ok {
 		return true
 	}
+	config.RLock()
+	defer config.RUnlock()
 	return objects.Range(pos1, pos2) <= config.ActionMinRange
 }
 
@@ -140,3 +248,11 @@ func importModule(path string) (flameres.ModuleData, error) {
 	}
 	return flamedata.ImportModuleDir(path)
 }
+
+// saveUser saves specified user in the users directory.
+func saveUser(usr *user.User) {
+	err := data.SaveUser(config.UsersPath, usr)
+	if err != nil {
+		log.Printf("Unable to save user: %s: %v", usr.ID(), err)
+	}
+}